@@ -2,96 +2,342 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"crypto/tls"
+	"errors"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 	ssogrpc "url-shortener/internal/clients/sso/grpc"
 	"url-shortener/internal/config"
+	"url-shortener/internal/http-server/handlers/auditlog"
+	"url-shortener/internal/http-server/handlers/health/healthz"
+	"url-shortener/internal/http-server/handlers/health/readyz"
+	"url-shortener/internal/http-server/handlers/httpsredirect"
+	"url-shortener/internal/http-server/handlers/loglevel"
+	"url-shortener/internal/http-server/handlers/me/quota"
 	"url-shortener/internal/http-server/handlers/redirect"
+	"url-shortener/internal/http-server/handlers/url/restore"
 	"url-shortener/internal/http-server/handlers/url/save"
+	"url-shortener/internal/http-server/handlers/url/update"
 	"url-shortener/internal/http-server/handlers/url/urldelete"
+	"url-shortener/internal/http-server/middleware/auth"
 	mwLogger "url-shortener/internal/http-server/middleware/logger"
+	mwMetrics "url-shortener/internal/http-server/middleware/metrics"
+	"url-shortener/internal/http-server/middleware/ratelimit"
+	mwTracing "url-shortener/internal/http-server/middleware/tracing"
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/certs"
+	"url-shortener/internal/lib/logger/handlers/sloglevel"
 	"url-shortener/internal/lib/logger/handlers/slogpretty"
+	"url-shortener/internal/lib/logger/handlers/slogredact"
+	"url-shortener/internal/lib/logger/handlers/slogsample"
+	"url-shortener/internal/lib/logger/handlers/slogtrace"
+	"url-shortener/internal/lib/logger/rotate"
 	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/tracing"
+	"url-shortener/internal/lib/trash"
+	"url-shortener/internal/lib/urlchain"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage"
 	"url-shortener/internal/storage/sqlite"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-)
-
-const (
-	envLocal = "local"
-	endDev   = "dev"
-	endProd  = "prod"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
 	// TODO init config: cleanenv - может читать из разных источников
 	cnf := config.MustLoad()
-	fmt.Println(cnf)
 
 	// TODO init logger: slog - в ядре с версии 1.21
-	log := setupLogger(cnf.Env)
+	// уровень меняется при перечитывании конфига, а временно - через /admin/log-level, в том числе для отдельных компонентов
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cnf.Level())
+	logLevels := sloglevel.NewLevels(logLevel)
+	// пароли, токены в url и тд не должны попадать в логи
+	redactor, err := slogredact.NewRedactor(slogredact.Options{
+		Keys:        cnf.LogRedact.Keys,
+		QueryParams: cnf.LogRedact.QueryParams,
+		Patterns:    cnf.LogRedact.Patterns,
+	})
+	if err != nil {
+		// конфиг уже проверен, сюда попасть не должны
+		slog.Error("failed to init log redaction", sl.Err(err))
+		os.Exit(1)
+	}
+
+	var logOut io.Writer = os.Stdout
+	if cnf.LogFile.Path != "" {
+		logFile, err := openLogFile(cnf.LogFile)
+		if err != nil {
+			slog.Error("failed to open log file", sl.Err(err))
+			os.Exit(1)
+		}
+		// последним: после остановки сервера еще пишем в лог
+		defer logFile.Close()
+		logOut = logFile
+	}
+
+	log := setupLogger(cnf, logOut, logLevels, redactor)
 	//log = log.With("env", cnf.Env) // добавляем параметр env ко всем логам
 	log.Info("starting application", slog.String("env", cnf.Env))
 	log.Debug("debug messages are enabled")
+	log.Debug("config loaded", slog.Any("config", cnf)) // без секретов
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName: "url-shortener",
+		Exporter:    cnf.Tracing.Exporter,
+		Endpoint:    cnf.Tracing.Endpoint,
+		Insecure:    cnf.Tracing.Insecure,
+		FilePath:    cnf.Tracing.FilePath,
+		SampleRatio: cnf.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Error("failed to init tracing", sl.Err(err))
+		os.Exit(1)
+	}
+
+	// фоновые задачи работают до остановки сервера
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	// SSO нужен только админским ручкам: без него редиректы и работа со ссылками продолжают работать
+	var ssoClient *ssogrpc.Client
+	if cnf.Clients.SSO.Address != "" {
+		retryCodes, err := ssogrpc.ParseCodes(cnf.Clients.SSO.RetryCodes)
+		if err != nil {
+			log.Error("invalid SSO retry codes", sl.Err(err))
+			os.Exit(1)
+		}
+
+		var ssoTLS *tls.Config
+		if !cnf.Clients.SSO.Insecure {
+			ssoCerts, err := certs.New(
+				log,
+				cnf.Clients.SSO.TLS.CertPath,
+				cnf.Clients.SSO.TLS.KeyPath,
+				cnf.Clients.SSO.TLS.CAPath,
+			)
+			if err != nil {
+				log.Error("failed to load SSO certificates", sl.Err(err))
+				os.Exit(1)
+			}
+			// перечитываем сертификаты без рестарта, новые соединения сразу используют новые файлы
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				ssoCerts.Watch(workersCtx, cnf.Clients.SSO.TLS.ReloadInterval)
+			}()
+
+			ssoTLS = ssoCerts.ClientConfig(ssoServerName(cnf.Clients.SSO))
+		} else {
+			log.Warn("SSO connection is not encrypted")
+		}
 
-	ssoClient, err := ssogrpc.New(
-		context.Background(),
+		// соединение ленивое, недоступность SSO при старте не ошибка
+		ssoClient, err = ssogrpc.New(context.Background(), log, cnf.Clients.SSO.Address, ssogrpc.Options{
+			Timeout:         cnf.Clients.SSO.Timeout,
+			RetriesCount:    cnf.Clients.SSO.RetriesCount,
+			RetryCodes:      retryCodes,
+			RetryBackoff:    cnf.Clients.SSO.RetryBackoff,
+			RetryJitter:     cnf.Clients.SSO.RetryJitter,
+			BreakerFailures: cnf.Clients.SSO.BreakerFailures,
+			BreakerTimeout:  cnf.Clients.SSO.BreakerTimeout,
+			TLS:             ssoTLS,
+		})
+		if err != nil {
+			log.Error("failed to init SSO client", sl.Err(err))
+			os.Exit(1)
+		}
+	} else {
+		log.Warn("SSO is disabled, admin routes will respond with 503")
+	}
+
+	// TODO init storage: sqlite
+	storage, err := sqlite.New(cnf.StoragePath, sqlite.Options{
+		NormalizeAliases: cnf.Aliases.CaseInsensitive,
+		OnConflict:       cnf.Aliases.OnConflict,
+	})
+	if err != nil {
+		log.Error("failed to init storage", sl.Err(err))
+		os.Exit(1)
+	}
+	reportAliasConflicts(log, storage)
+	_ = storage
+
+	// окончательно удаляем ссылки из корзины
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		trash.RunPurge(workersCtx, log, storage, cnf.Trash.Retention, cnf.Trash.PurgeInterval)
+	}()
+
+	urlPolicy, err := urlpolicy.New(
 		log,
-		cnf.Clients.SSO.Address,
-		cnf.Clients.SSO.Timeout,
-		cnf.Clients.SSO.RetriesCount,
+		cnf.URLPolicy.Schemes,
+		cnf.URLPolicy.BlocklistPath,
+		cnf.URLPolicy.AllowlistPath,
 	)
 	if err != nil {
-		log.Error("failed to init SSO client", sl.Err(err))
+		log.Error("failed to init url policy", sl.Err(err))
 		os.Exit(1)
 	}
+	// перечитываем списки доменов без рестарта
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		urlPolicy.Watch(workersCtx, cnf.URLPolicy.ReloadInterval)
+	}()
 
-	// просто проверим работу grpc
-	isAdmin, err := ssoClient.IsAdmin(context.Background(), 1)
+	urlChecker := urlpolicy.All{urlPolicy}
+	if cnf.URLPolicy.BlockPrivate {
+		networkGuard, err := newNetworkGuard(cnf)
+		if err != nil {
+			log.Error("failed to init url network guard", sl.Err(err))
+			os.Exit(1)
+		}
+		urlChecker = append(urlChecker, networkGuard)
+	}
+
+	chainResolver, err := urlchain.New(
+		storage,
+		cnf.HTTPServer.PublicHosts,
+		cnf.URLPolicy.ChainMode,
+		cnf.URLPolicy.MaxChainDepth,
+	)
 	if err != nil {
-		log.Error("grpc failed", sl.Err(err))
+		log.Error("failed to init short link chain resolver", sl.Err(err))
 		os.Exit(1)
 	}
-	log.Info("grpc 'IsAdmin'", slog.Bool("isAdmin", isAdmin))
 
-	// TODO init storage: sqlite
-	storage, err := sqlite.New(cnf.StoragePath)
+	aliasPolicy, err := alias.NewPolicy(cnf.Aliases.Reserved, cnf.Aliases.BannedWordsPath)
 	if err != nil {
-		log.Error("failed to init storage", sl.Err(err))
+		log.Error("failed to init alias policy", sl.Err(err))
 		os.Exit(1)
 	}
-	_ = storage
 
 	// TODO init router: chi, "chi render"
 	router := chi.NewRouter()
 	// добавляет идентификатор каждому запросу
 	router.Use(middleware.RequestID)
 	router.Use(middleware.RealIP) // ip пользователя
+	// до логгера, чтобы в логе запроса был trace_id
+	router.Use(mwTracing.New())
 
 	// лог запросов из коробки. Проблема, что у нас свой логгер
 	//router.Use(middleware.Logger)
-	// своя реализация логгера для middleware
-	router.Use(mwLogger.New(log))
+	// своя реализация логгера для middleware: slog в общий лог, combined и ecs в stdout, либо в отдельный файл
+	accessLog := log
+	var accessOut io.Writer = os.Stdout
+	if cnf.AccessLog.File.Path != "" {
+		accessFile, err := openLogFile(cnf.AccessLog.File)
+		if err != nil {
+			log.Error("failed to open access log file", sl.Err(err))
+			os.Exit(1)
+		}
+		defer accessFile.Close()
 
+		accessOut = accessFile
+		accessLog = setupLogger(cnf, accessFile, logLevels, redactor)
+	}
+	router.Use(mwLogger.New(accessLog, mwLogger.Options{
+		Format: cnf.AccessLog.Format,
+		Out:    accessOut,
+		Redact: redactor.String,
+	}))
+
+	if cnf.Metrics.Enabled {
+		router.Use(mwMetrics.New())
+	}
 	router.Use(middleware.Recoverer) // приложение не падает при плохом запросе
 	router.Use(middleware.URLFormat) // можно писать в хендлере красивые урлы типа /articles/{id}. И обращаться по {id}
 
+	// BasicAuth + пользователь с ролью в контексте запроса
+	users := auth.NewUsers(authUsers(cnf))
+	authMw := auth.New("url-shortener", users)
+	urlLimiter := ratelimit.NewLimiter(cnf.RateLimit.URL.RPS, cnf.RateLimit.URL.Burst)
+	redirectLimiter := ratelimit.NewLimiter(cnf.RateLimit.Redirect.RPS, cnf.RateLimit.Redirect.Burst)
+	quotas := linkQuotas(cnf)
+
 	router.Route("/url", func(r chi.Router) {
-		r.Use(middleware.BasicAuth("url-shortener", map[string]string{
-			cnf.HTTPServer.User: cnf.HTTPServer.Password,
-			//cnf.HTTPServer.User: cnf.HTTPServer.Password, // может добавить других пользователей
-		}))
+		r.Use(authMw)
+		// после BasicAuth, чтобы лимит считался на пользователя
+		r.Use(ratelimit.New(log, urlLimiter, ratelimit.ByUser))
+
+		r.Post("/", save.New(log, storage, quotas, urlChecker, chainResolver, aliasPolicy, storage))
+		r.Put("/{alias}", update.New(log, storage, storage, urlChecker, chainResolver))
+		r.Delete("/{alias}", urldelete.New(log, storage, storage))
+		r.Post("/{alias}/restore", restore.New(log, storage, storage, quotas, users))
+	})
+
+	router.Route("/me", func(r chi.Router) {
+		r.Use(authMw)
+
+		r.Get("/quota", quota.New(log, storage, quotas))
+	})
+
+	router.Route("/audit", func(r chi.Router) {
+		r.Use(authMw)
+		// права администратора проверяем в SSO
+		r.Use(auth.AdminOnly(log, adminChecker(ssoClient)))
 
-		r.Post("/", save.New(log, storage))
-		r.Delete("/{alias}", urldelete.New(log, storage))
+		r.Get("/", auditlog.New(log, storage))
 	})
 
-	router.Get("/{alias}", redirect.New(log, storage))
+	router.Route("/admin/log-level", func(r chi.Router) {
+		r.Use(authMw)
+		r.Use(auth.AdminOnly(log, adminChecker(ssoClient)))
 
-	log.Info("starting server", slog.String("address", cnf.Address))
+		r.Get("/", loglevel.NewGet(logLevels))
+		r.Put("/", loglevel.New(log, logLevels, cnf.LogLevelTTL))
+		r.Delete("/", loglevel.NewReset(log, logLevels))
+	})
+
+	// для балансировщика: жив ли процесс и готов ли принимать запросы
+	var shuttingDown atomic.Bool
+	readyDeps := map[string]readyz.Pinger{"storage": storage}
+	if cnf.Health.CheckSSO && ssoClient != nil {
+		readyDeps["sso"] = ssoClient
+	}
+	router.Get("/healthz", healthz.New())
+	router.Get("/readyz", readyz.New(log, &shuttingDown, cnf.Health.Timeout, readyDeps))
+
+	// если задан отдельный адрес, /metrics доступны только там
+	var adminSrv *http.Server
+	if cnf.Metrics.Enabled {
+		if cnf.Metrics.Address == "" {
+			router.Handle("/metrics", promhttp.Handler())
+		} else {
+			adminRouter := chi.NewRouter()
+			adminRouter.Handle("/metrics", promhttp.Handler())
+
+			adminSrv = &http.Server{
+				Addr:         cnf.Metrics.Address,
+				Handler:      adminRouter,
+				ReadTimeout:  cnf.Timeout,
+				WriteTimeout: cnf.Timeout,
+			}
+		}
+	}
+
+	router.With(ratelimit.New(log, redirectLimiter, ratelimit.ByIP)).
+		Get("/{alias}", redirect.New(log, storage))
+
+	// alias не должен совпадать с путями роутера, в том числе будущими
+	if err := reserveRoutes(router, aliasPolicy); err != nil {
+		log.Error("failed to reserve router paths", sl.Err(err))
+		os.Exit(1)
+	}
 
 	// TODO run server
 	srv := http.Server{
@@ -102,43 +348,342 @@ func main() {
 		IdleTimeout:  cnf.IdleTimeout,
 	}
 
-	if err := srv.ListenAndServe(); err != nil {
-		log.Error("failed to start server")
+	// HTTPS, если заданы сертификат и ключ. Сертификат перечитывается без рестарта
+	useTLS := cnf.TLS.CertPath != "" || cnf.TLS.KeyPath != ""
+	var redirectSrv *http.Server
+	if useTLS {
+		serverCerts, err := certs.New(log, cnf.TLS.CertPath, cnf.TLS.KeyPath, "")
+		if err != nil {
+			log.Error("failed to load server certificate", sl.Err(err))
+			os.Exit(1)
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			serverCerts.Watch(workersCtx, cnf.TLS.ReloadInterval)
+		}()
+
+		srv.TLSConfig, err = serverTLSConfig(serverCerts, cnf.TLS)
+		if err != nil {
+			log.Error("invalid server TLS config", sl.Err(err))
+			os.Exit(1)
+		}
+		if !cnf.TLS.HTTP2 {
+			// непустой TLSNextProto выключает HTTP/2
+			srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		}
+
+		if cnf.TLS.RedirectAddress != "" {
+			redirectSrv = &http.Server{
+				Addr:         cnf.TLS.RedirectAddress,
+				Handler:      httpsredirect.New(cnf.Address),
+				ReadTimeout:  cnf.Timeout,
+				WriteTimeout: cnf.Timeout,
+				IdleTimeout:  cnf.IdleTimeout,
+			}
+		}
+	}
+
+	// blocklist'ы, лимиты, уровень логов и пользователей можно поменять без рестарта: SIGHUP или изменение файла
+	reloader := config.NewReloader(log, os.Getenv(config.PathEnv), cnf, func(cnf *config.Config) error {
+		// единственный шаг, который может не получиться, поэтому первым: при ошибке не меняется ничего
+		if err := urlPolicy.Update(cnf.URLPolicy.Schemes, cnf.URLPolicy.BlocklistPath, cnf.URLPolicy.AllowlistPath); err != nil {
+			return err
+		}
+
+		urlLimiter.SetLimit(cnf.RateLimit.URL.RPS, cnf.RateLimit.URL.Burst)
+		redirectLimiter.SetLimit(cnf.RateLimit.Redirect.RPS, cnf.RateLimit.Redirect.Burst)
+		users.Set(authUsers(cnf))
+		logLevel.Set(cnf.Level())
+
+		return nil
+	})
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		reloader.Run(workersCtx, hup, cnf.Reload.WatchInterval)
+	}()
+
+	// SIGTERM присылает systemd при остановке сервиса
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if adminSrv != nil {
+		log.Info("starting admin server", slog.String("address", adminSrv.Addr))
+
+		go func() {
+			if err := adminSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("failed to start admin server", sl.Err(err))
+			}
+		}()
+	}
+
+	if redirectSrv != nil {
+		log.Info("starting HTTPS redirect server", slog.String("address", redirectSrv.Addr))
+
+		go func() {
+			if err := redirectSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("failed to start HTTPS redirect server", sl.Err(err))
+			}
+		}()
+	}
+
+	log.Info("starting server", slog.String("address", cnf.Address), slog.Bool("tls", useTLS))
+
+	serverErr := make(chan error, 1)
+	go func() {
+		var err error
+		if useTLS {
+			// сертификат берется из srv.TLSConfig
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	select {
+	case <-ctx.Done():
+		log.Info("stopping server",
+			slog.Duration("drain_delay", cnf.ShutdownDelay),
+			slog.Duration("drain_timeout", cnf.ShutdownTimeout),
+		)
+	case err := <-serverErr:
+		log.Error("failed to start server", sl.Err(err))
+	}
+
+	// /readyz отвечает 503, пока дожидаемся текущих запросов
+	shuttingDown.Store(true)
+
+	// сервер еще принимает запросы: даем балансировщику увидеть 503 и убрать инстанс,
+	// иначе новые соединения получат отказ. Если сервер не запустился, ждать некого
+	if ctx.Err() != nil && cnf.ShutdownDelay > 0 {
+		time.Sleep(cnf.ShutdownDelay)
+	}
+
+	// порядок важен: сначала дожидаемся текущих запросов, потом фоновых задач,
+	// и только потом закрываем storage и SSO, которыми они пользуются
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cnf.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error("failed to drain requests", sl.Err(err))
+	}
+
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(shutdownCtx); err != nil {
+			log.Error("failed to stop admin server", sl.Err(err))
+		}
+	}
+
+	if redirectSrv != nil {
+		if err := redirectSrv.Shutdown(shutdownCtx); err != nil {
+			log.Error("failed to stop HTTPS redirect server", sl.Err(err))
+		}
+	}
+
+	stopWorkers()
+	workers.Wait()
+
+	if err := storage.Close(); err != nil {
+		log.Error("failed to close storage", sl.Err(err))
+	}
+
+	if ssoClient != nil {
+		if err := ssoClient.Close(); err != nil {
+			log.Error("failed to close SSO client", sl.Err(err))
+		}
+	}
+
+	// последним, чтобы отправить span'ы остановки. shutdownCtx мог уже истечь
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelTracing()
+
+	if err := shutdownTracing(tracingCtx); err != nil {
+		log.Error("failed to flush traces", sl.Err(err))
 	}
 
-	log.Error("server stopped")
+	log.Info("server stopped")
 }
 
-// лог (вид и уровень) зависит от окружения: dev, prod и тд
-func setupLogger(env string) *slog.Logger {
+// вид лога зависит от окружения: dev, prod и тд. Уровень решает sloglevel, поэтому сами handler'ы пропускают все
+func setupLogger(cnf *config.Config, out io.Writer, levels *sloglevel.Levels, redactor *slogredact.Redactor) *slog.Logger {
 	var log *slog.Logger
-	switch env {
-	case envLocal:
-		log = setupPrettySlog()
+	switch cnf.Env {
+	case config.EnvLocal:
+		log = setupPrettySlog(out)
 		//log = slog.New(
 		//    slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		//)
-	case endDev: // для dev стенда
+	case config.EnvDev: // для dev стенда
 		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+			slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		)
-	case endProd:
+	case config.EnvProd:
 		log = slog.New(
-			slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}),
+			slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}),
 		)
 	}
 
-	return log
+	// частые записи сэмплируем до скрытия секретов, чтобы не тратить на них время
+	handler := slogsample.New(slogredact.New(log.Handler(), redactor), slogsample.Options{
+		First:      cnf.LogSampling.First,
+		Thereafter: cnf.LogSampling.Thereafter,
+		Tick:       cnf.LogSampling.Tick,
+	})
+
+	// trace_id и span_id для записей с контекстом запроса. Секреты скрываются перед самим выводом
+	return slog.New(slogtrace.New(sloglevel.New(handler, levels)))
+}
+
+func authUsers(cnf *config.Config) map[string]auth.Credentials {
+	users := map[string]auth.Credentials{
+		cnf.HTTPServer.User: {Password: cnf.HTTPServer.Password, Role: auth.RoleAdmin, UID: cnf.HTTPServer.UID},
+	}
+
+	for _, u := range cnf.HTTPServer.Users {
+		users[u.Name] = auth.Credentials{Password: u.Password, Role: u.Role, UID: u.UID}
+	}
+
+	return users
+}
+
+// nil *ssogrpc.Client в интерфейсе не равен nil, а AdminOnly проверяет именно nil
+func adminChecker(ssoClient *ssogrpc.Client) auth.AdminChecker {
+	if ssoClient == nil {
+		return nil
+	}
+
+	return ssoClient
+}
+
+// serverTLSConfig - версия и наборы шифров из конфига поверх сертификата из Store
+func serverTLSConfig(serverCerts *certs.Store, cnf config.ServerTLS) (*tls.Config, error) {
+	tlsConfig := serverCerts.ServerConfig()
+
+	minVersion, err := certs.ParseVersion(cnf.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.MinVersion = minVersion
+
+	if len(cnf.CipherSuites) > 0 {
+		tlsConfig.CipherSuites, err = certs.ParseCipherSuites(cnf.CipherSuites)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tlsConfig, nil
+}
+
+// ssoServerName - имя в сертификате SSO. Для ip адреса SNI не отправляется, поэтому имя берем из адреса явно
+func ssoServerName(cnf config.Client) string {
+	if cnf.TLS.ServerName != "" {
+		return cnf.TLS.ServerName
+	}
+
+	host, _, err := net.SplitHostPort(cnf.Address)
+	if err != nil {
+		return cnf.Address
+	}
+
+	return host
+}
+
+func linkQuotas(cnf *config.Config) map[string]storage.Quota {
+	quotas := make(map[string]storage.Quota, len(cnf.Quotas))
+
+	for role, q := range cnf.Quotas {
+		quotas[role] = storage.Quota{MaxActive: q.MaxActive, MaxPerDay: q.MaxPerDay}
+	}
+
+	return quotas
+}
+
+// reportAliasConflicts - что стало с alias, совпавшими после включения aliases.case_insensitive.
+// Переименования пишем в журнал изменений, чтобы владелец мог найти свою ссылку
+func reportAliasConflicts(log *slog.Logger, s *sqlite.Storage) {
+	for _, c := range s.Conflicts() {
+		log.Warn("alias conflict",
+			slog.String("alias", c.Alias),
+			slog.String("kept", c.Kept),
+			slog.String("resolution", c.Resolution),
+			slog.String("new_alias", c.NewAlias),
+			slog.Bool("deleted", c.Deleted),
+		)
+
+		if c.Resolution != storage.ResolutionRenamed {
+			continue
+		}
+
+		if err := s.SaveAuditEntry(context.Background(), storage.AuditEntry{
+			Actor:    audit.ActorSystem,
+			Action:   audit.ActionRename,
+			Alias:    c.NewAlias,
+			OldValue: c.Alias,
+			NewValue: c.NewAlias,
+		}); err != nil {
+			log.Error("failed to save audit entry", sl.Err(err))
+		}
+	}
+}
+
+func newNetworkGuard(cnf *config.Config) (*urlpolicy.NetworkGuard, error) {
+	var resolver urlpolicy.Resolver
+	if cnf.URLPolicy.Resolve {
+		resolver = net.DefaultResolver
+	}
+
+	serviceHosts := append([]string{cnf.HTTPServer.Address}, cnf.URLPolicy.ServiceHosts...)
+
+	// public_hosts в allowlist не добавляем: пути сервиса на них отсекает urlchain,
+	// а внутренние адреса (localhost и тд) должны отсекаться и для них
+	return urlpolicy.NewNetworkGuard(
+		resolver,
+		cnf.URLPolicy.ResolveTimeout,
+		serviceHosts,
+		cnf.URLPolicy.InternalAllowlist,
+	)
+}
+
+// резервируем первые сегменты путей: /url/{alias} -> url. Параметры ({alias}) пропускаем
+func reserveRoutes(router chi.Routes, aliasPolicy *alias.Policy) error {
+	return chi.Walk(router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+		if segment != "" && !strings.HasPrefix(segment, "{") {
+			aliasPolicy.Reserve(segment)
+		}
+
+		return nil
+	})
+}
+
+func openLogFile(f config.LogFile) (*rotate.Writer, error) {
+	return rotate.New(rotate.Options{
+		Path:       f.Path,
+		MaxSize:    int64(f.MaxSizeMB) << 20,
+		Interval:   f.RotateInterval,
+		MaxBackups: f.MaxBackups,
+		Compress:   f.Compress,
+	})
 }
 
-func setupPrettySlog() *slog.Logger {
+func setupPrettySlog(out io.Writer) *slog.Logger {
 	opts := slogpretty.PrettyHandlerOptions{
 		SlogOpts: &slog.HandlerOptions{
 			Level: slog.LevelDebug,
 		},
+		// в файле цвета не нужны
+		NoColor: out != os.Stdout,
 	}
 
-	handler := opts.NewPrettyHandler(os.Stdout)
+	handler := opts.NewPrettyHandler(out)
 
 	return slog.New(handler)
 }
@@ -0,0 +1,140 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // вызовы проходят
+	breakerOpen                         // вызовы сразу отбиваются
+	breakerHalfOpen                     // пропускаем один пробный вызов
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker - circuit breaker: после failures неудачных вызовов подряд перестает ходить в SSO на timeout,
+// потом пропускает один пробный вызов. Так при лежащем SSO админские ручки сразу отвечают 503, а не ждут ретраев
+type breaker struct {
+	log       *slog.Logger
+	threshold int
+	timeout   time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func newBreaker(log *slog.Logger, threshold int, timeout time.Duration) *breaker {
+	return &breaker{
+		log:       log,
+		threshold: threshold,
+		timeout:   timeout,
+	}
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.timeout {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+
+		return true
+	case breakerHalfOpen:
+		// пробный вызов еще не вернулся
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *breaker) done(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	code := status.Code(err)
+	switch {
+	case code == codes.Canceled:
+		// вызов отменили мы сами, о SSO это ничего не говорит. Пробный вызов не удался - ждем timeout заново
+		if b.state == breakerHalfOpen {
+			b.setState(breakerOpen)
+		}
+	case isFailure(code):
+		b.failures++
+		if b.state == breakerHalfOpen || b.failures >= b.threshold {
+			b.setState(breakerOpen)
+		}
+	default:
+		// ответ с бизнес-ошибкой (NotFound и т.п.) - SSO жив
+		b.failures = 0
+		b.setState(breakerClosed)
+	}
+}
+
+func (b *breaker) setState(state breakerState) {
+	if b.state == state {
+		return
+	}
+
+	b.log.Warn("sso circuit breaker state changed",
+		slog.String("from", b.state.String()),
+		slog.String("to", state.String()),
+		slog.Int("failures", b.failures),
+	)
+	b.state = state
+	// timeout отсчитывается от каждого размыкания, в том числе после пробного вызова
+	if state == breakerOpen {
+		b.openedAt = time.Now()
+	}
+}
+
+// isFailure - ошибки, которые говорят о недоступности SSO
+func isFailure(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	}
+
+	return false
+}
+
+func (b *breaker) interceptor(
+	ctx context.Context,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	if !b.allow() {
+		return status.Error(codes.Unavailable, "sso circuit breaker is open")
+	}
+
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	b.done(err)
+
+	return err
+}
@@ -2,59 +2,144 @@ package grpc
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	grpclog "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	grpcretry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
 
 	ssov1 "github.com/vrnvgasu/protos/gen/go/sso"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"url-shortener/internal/clients/sso"
+	"url-shortener/internal/lib/metrics"
 )
 
 type Client struct {
 	api ssov1.AuthClient
+	cc  *grpc.ClientConn
 	log *slog.Logger
 }
 
+// Options - настройки соединения с SSO
+type Options struct {
+	Timeout      time.Duration // на одну попытку
+	RetriesCount int
+	RetryCodes   []codes.Code  // при каких кодах повторяем запрос, nil - DefaultRetryCodes
+	RetryBackoff time.Duration // пауза перед первым повтором, дальше растет экспоненциально
+	RetryJitter  float64       // случайное отклонение паузы: 0.2 - ±20%
+
+	BreakerFailures int           // сколько неудачных вызовов подряд размыкают breaker, 0 - без breaker'а
+	BreakerTimeout  time.Duration // сколько breaker разомкнут до пробного вызова
+
+	TLS         *tls.Config       // nil - незащищенное соединение
+	DialOptions []grpc.DialOption // дополнительные опции соединения, например bufconn в тестах
+}
+
+// DefaultRetryCodes - временные ошибки, после которых есть смысл повторить запрос.
+// NotFound и прочие ответы SSO по существу не ретраим: повтор вернет то же самое
+var DefaultRetryCodes = []codes.Code{codes.Unavailable, codes.Aborted, codes.DeadlineExceeded}
+
 func New(
 	ctx context.Context,
 	log *slog.Logger,
 	addr string,
-	timeout time.Duration,
-	retriesCount int,
+	opts Options,
 ) (*Client, error) {
 	const op = "grpc.New"
 
+	retryCodes := opts.RetryCodes
+	if retryCodes == nil {
+		retryCodes = DefaultRetryCodes
+	}
+
 	retryOpts := []grpcretry.CallOption{
-		grpcretry.WithCodes(codes.NotFound, codes.Aborted, codes.DeadlineExceeded), // делаем ретраи только при таких ошибка сервера
-		grpcretry.WithMax(uint(retriesCount)),
-		grpcretry.WithPerRetryTimeout(timeout),
+		grpcretry.WithCodes(retryCodes...), // делаем ретраи только при таких ошибка сервера
+		grpcretry.WithMax(uint(opts.RetriesCount)),
+		grpcretry.WithPerRetryTimeout(opts.Timeout),
+		// паузы между попытками растут экспоненциально, jitter разводит ретраи разных запросов по времени
+		grpcretry.WithBackoff(grpcretry.BackoffExponentialWithJitter(opts.RetryBackoff, opts.RetryJitter)),
 	}
 	logOpts := []grpclog.Option{
 		grpclog.WithLogOnEvents(grpclog.PayloadReceived, grpclog.PayloadSent),
 	}
 
-	cc, err := grpc.DialContext(ctx, addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithChainUnaryInterceptor( // цепочка интерсепторов
-			grpclog.UnaryClientInterceptor(InterceptorLogger(log), logOpts...),
-			grpcretry.UnaryClientInterceptor(retryOpts...),
-		),
-	) // для простоты делаем незащищенное соединение
+	interceptors := []grpc.UnaryClientInterceptor{
+		metricsInterceptor, // первым, чтобы считать итог вызова после ретраев
+		tracingInterceptor, // один span на вызов вместе с ретраями
+	}
+	if opts.BreakerFailures > 0 {
+		// перед ретраями: breaker считает итог вызова, а не каждую попытку
+		interceptors = append(interceptors, newBreaker(log, opts.BreakerFailures, opts.BreakerTimeout).interceptor)
+	}
+	interceptors = append(interceptors,
+		grpclog.UnaryClientInterceptor(InterceptorLogger(log), logOpts...),
+		grpcretry.UnaryClientInterceptor(retryOpts...),
+	)
+
+	creds := insecure.NewCredentials()
+	if opts.TLS != nil {
+		creds = credentials.NewTLS(opts.TLS)
+	}
+
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(interceptors...), // цепочка интерсепторов
+	}
+	dialOpts = append(dialOpts, opts.DialOptions...)
+
+	// соединение ленивое: SSO может быть недоступен при старте
+	cc, err := grpc.DialContext(ctx, addr, dialOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return &Client{
 		api: ssov1.NewAuthClient(cc),
+		cc:  cc,
 		log: log,
 	}, nil
 }
 
+// ParseCodes переводит названия кодов из конфига (Unavailable, DeadlineExceeded, ...) в codes.Code
+func ParseCodes(names []string) ([]codes.Code, error) {
+	const op = "grpc.ParseCodes"
+
+	result := make([]codes.Code, 0, len(names))
+	for _, name := range names {
+		code, ok := parseCode(strings.TrimSpace(name))
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown code %q", op, name)
+		}
+		result = append(result, code)
+	}
+
+	return result, nil
+}
+
+func parseCode(name string) (codes.Code, bool) {
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if strings.EqualFold(c.String(), name) {
+			return c, true
+		}
+	}
+
+	return 0, false
+}
+
 func (c *Client) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 	const op = "grpc.IsAdmin"
 
@@ -62,12 +147,120 @@ func (c *Client) IsAdmin(ctx context.Context, userID int64) (bool, error) {
 		UserId: userID,
 	})
 	if err != nil {
+		switch status.Code(err) {
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return false, fmt.Errorf("%s: %w: %w", op, sso.ErrUnavailable, err)
+		}
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return resp.IsAdmin, err
 }
 
+// Ping ждет, пока соединение с SSO будет установлено, но не дольше ctx
+func (c *Client) Ping(ctx context.Context) error {
+	const op = "grpc.Ping"
+
+	for {
+		state := c.cc.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Idle:
+			// соединение ленивое, без запросов не устанавливается
+			c.cc.Connect()
+		case connectivity.Shutdown:
+			return fmt.Errorf("%s: connection is closed", op)
+		}
+
+		if !c.cc.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("%s: sso is unavailable, state %s", op, state)
+		}
+	}
+}
+
+// Close закрывает соединение с SSO
+func (c *Client) Close() error {
+	const op = "grpc.Close"
+
+	if err := c.cc.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// считаем вызовы SSO по методу и коду ответа
+func metricsInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	metrics.SSOCalls.WithLabelValues(method, status.Code(err).String()).Inc()
+
+	return err
+}
+
+var tracer = otel.Tracer("url-shortener/internal/clients/sso/grpc")
+
+// span на вызов SSO, traceparent передаем в metadata, чтобы SSO продолжил trace
+func tracingInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply any,
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	ctx, span := tracer.Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.RPCSystemGRPC),
+	)
+	defer span.End()
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+
+	err := invoker(metadata.NewOutgoingContext(ctx, md), method, req, reply, cc, opts...)
+
+	st := status.Convert(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(st.Code())))
+	if err != nil {
+		span.SetStatus(otelcodes.Error, st.Message())
+	}
+
+	return err
+}
+
+// metadataCarrier - propagation.TextMapCarrier поверх gRPC metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+
+	return ""
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
 // обертка интерсептора над нашим логгером
 func InterceptorLogger(l *slog.Logger) grpclog.Logger {
 	return grpclog.LoggerFunc(func(ctx context.Context, level grpclog.Level, msg string, fields ...any) {
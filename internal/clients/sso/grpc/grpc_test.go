@@ -0,0 +1,351 @@
+package grpc_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	ssov1 "github.com/vrnvgasu/protos/gen/go/sso"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/grpc/testdata"
+
+	"url-shortener/internal/clients/sso"
+	ssogrpc "url-shortener/internal/clients/sso/grpc"
+	"url-shortener/internal/lib/certs"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+// fakeAuth - SSO в памяти: отвечает ошибками из errs по очереди, потом успехом
+type fakeAuth struct {
+	ssov1.UnimplementedAuthServer
+
+	mu    sync.Mutex
+	errs  []error
+	calls int
+}
+
+func (f *fakeAuth) IsAdmin(_ context.Context, _ *ssov1.IsAdminRequest) (*ssov1.IsAdminResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+
+		return nil, err
+	}
+
+	return &ssov1.IsAdminResponse{IsAdmin: true}, nil
+}
+
+func (f *fakeAuth) setErrs(errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.errs = errs
+}
+
+func (f *fakeAuth) callsCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls
+}
+
+func newClient(t *testing.T, auth *fakeAuth, opts ssogrpc.Options, serverOpts ...grpc.ServerOption) *ssogrpc.Client {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(serverOpts...)
+	ssov1.RegisterAuthServer(srv, auth)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	opts.DialOptions = append(opts.DialOptions, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}))
+
+	client, err := ssogrpc.New(context.Background(), slogdiscard.NewDiscardLogger(), "passthrough:///bufnet", opts)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+func TestClient_IsAdmin_Retries(t *testing.T) {
+	cases := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+		wantCode  codes.Code
+	}{
+		{
+			name:      "Success",
+			wantCalls: 1,
+		},
+		{
+			name: "Retry on Unavailable",
+			errs: []error{
+				status.Error(codes.Unavailable, "down"),
+				status.Error(codes.Unavailable, "down"),
+			},
+			wantCalls: 3,
+		},
+		{
+			name: "Retries exhausted",
+			errs: []error{
+				status.Error(codes.Unavailable, "down"),
+				status.Error(codes.Unavailable, "down"),
+				status.Error(codes.Unavailable, "down"),
+				status.Error(codes.Unavailable, "down"),
+			},
+			wantCalls: 3,
+			wantErr:   sso.ErrUnavailable,
+			wantCode:  codes.Unavailable,
+		},
+		{
+			name:      "No retry on NotFound",
+			errs:      []error{status.Error(codes.NotFound, "user not found")},
+			wantCalls: 1,
+			wantCode:  codes.NotFound,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			auth := &fakeAuth{errs: tc.errs}
+			client := newClient(t, auth, ssogrpc.Options{
+				Timeout:      time.Second,
+				RetriesCount: 3,
+				RetryBackoff: time.Millisecond,
+				RetryJitter:  0.2,
+			})
+
+			isAdmin, err := client.IsAdmin(context.Background(), 1)
+			require.Equal(t, tc.wantCalls, auth.callsCount())
+
+			if tc.wantCode == codes.OK {
+				require.NoError(t, err)
+				require.True(t, isAdmin)
+
+				return
+			}
+
+			require.Error(t, err)
+			require.Equal(t, tc.wantCode, status.Code(err))
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NotErrorIs(t, err, sso.ErrUnavailable)
+			}
+		})
+	}
+}
+
+func TestClient_IsAdmin_Breaker(t *testing.T) {
+	t.Parallel()
+
+	auth := &fakeAuth{}
+	client := newClient(t, auth, ssogrpc.Options{
+		Timeout:         time.Second,
+		RetriesCount:    1,
+		RetryBackoff:    time.Millisecond,
+		BreakerFailures: 2,
+		BreakerTimeout:  100 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	auth.setErrs(
+		status.Error(codes.Unavailable, "down"),
+		status.Error(codes.Unavailable, "down"),
+		status.Error(codes.Unavailable, "down"),
+	)
+
+	// две неудачи подряд размыкают breaker
+	for i := 0; i < 2; i++ {
+		_, err := client.IsAdmin(ctx, 1)
+		require.ErrorIs(t, err, sso.ErrUnavailable)
+	}
+	require.Equal(t, 2, auth.callsCount())
+
+	// разомкнут: в SSO не ходим
+	_, err := client.IsAdmin(ctx, 1)
+	require.ErrorIs(t, err, sso.ErrUnavailable)
+	require.Equal(t, 2, auth.callsCount())
+
+	// пробный вызов неудачный - снова разомкнут
+	time.Sleep(150 * time.Millisecond)
+	_, err = client.IsAdmin(ctx, 1)
+	require.ErrorIs(t, err, sso.ErrUnavailable)
+	require.Equal(t, 3, auth.callsCount())
+
+	_, err = client.IsAdmin(ctx, 1)
+	require.ErrorIs(t, err, sso.ErrUnavailable)
+	require.Equal(t, 3, auth.callsCount())
+
+	// SSO поднялся: пробный вызов замыкает breaker
+	time.Sleep(150 * time.Millisecond)
+	isAdmin, err := client.IsAdmin(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, isAdmin)
+
+	isAdmin, err = client.IsAdmin(ctx, 1)
+	require.NoError(t, err)
+	require.True(t, isAdmin)
+	require.Equal(t, 5, auth.callsCount())
+}
+
+func TestClient_IsAdmin_BreakerCanceledProbe(t *testing.T) {
+	t.Parallel()
+
+	auth := &fakeAuth{}
+	client := newClient(t, auth, ssogrpc.Options{
+		Timeout:         time.Second,
+		RetriesCount:    1,
+		BreakerFailures: 1,
+		BreakerTimeout:  100 * time.Millisecond,
+	})
+
+	auth.setErrs(status.Error(codes.Unavailable, "down"))
+
+	_, err := client.IsAdmin(context.Background(), 1)
+	require.ErrorIs(t, err, sso.ErrUnavailable)
+
+	// пробный вызов отменили - ждем timeout заново, а не пробуем сразу
+	time.Sleep(150 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _ = client.IsAdmin(ctx, 1)
+
+	_, err = client.IsAdmin(context.Background(), 1)
+	require.ErrorIs(t, err, sso.ErrUnavailable)
+	require.Equal(t, 1, auth.callsCount())
+
+	time.Sleep(150 * time.Millisecond)
+	isAdmin, err := client.IsAdmin(context.Background(), 1)
+	require.NoError(t, err)
+	require.True(t, isAdmin)
+}
+
+func TestClient_IsAdmin_BreakerIgnoresBusinessErrors(t *testing.T) {
+	t.Parallel()
+
+	auth := &fakeAuth{}
+	client := newClient(t, auth, ssogrpc.Options{
+		Timeout:         time.Second,
+		RetriesCount:    1,
+		BreakerFailures: 2,
+		BreakerTimeout:  time.Minute,
+	})
+
+	auth.setErrs(
+		status.Error(codes.Unavailable, "down"),
+		status.Error(codes.NotFound, "user not found"),
+		status.Error(codes.Unavailable, "down"),
+	)
+
+	// ответ SSO по существу сбрасывает счетчик неудач
+	for i := 0; i < 3; i++ {
+		_, _ = client.IsAdmin(context.Background(), 1)
+	}
+
+	isAdmin, err := client.IsAdmin(context.Background(), 1)
+	require.NoError(t, err)
+	require.True(t, isAdmin)
+	require.Equal(t, 4, auth.callsCount())
+}
+
+func TestClient_IsAdmin_MTLS(t *testing.T) {
+	serverCert, err := tls.LoadX509KeyPair(
+		testdata.Path("x509/server1_cert.pem"),
+		testdata.Path("x509/server1_key.pem"),
+	)
+	require.NoError(t, err)
+
+	clientCA, err := os.ReadFile(testdata.Path("x509/client_ca_cert.pem"))
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	require.True(t, clientCAs.AppendCertsFromPEM(clientCA))
+
+	serverCreds := grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}))
+
+	cases := []struct {
+		name       string
+		certPath   string
+		keyPath    string
+		serverName string
+		wantErr    bool
+	}{
+		{
+			name:       "Success",
+			certPath:   testdata.Path("x509/client1_cert.pem"),
+			keyPath:    testdata.Path("x509/client1_key.pem"),
+			serverName: "sso.test.example.com",
+		},
+		{
+			name:       "No client certificate",
+			serverName: "sso.test.example.com",
+			wantErr:    true,
+		},
+		{
+			name:       "Wrong server name",
+			certPath:   testdata.Path("x509/client1_cert.pem"),
+			keyPath:    testdata.Path("x509/client1_key.pem"),
+			serverName: "sso.local",
+			wantErr:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := certs.New(slogdiscard.NewDiscardLogger(), tc.certPath, tc.keyPath, testdata.Path("x509/server_ca_cert.pem"))
+			require.NoError(t, err)
+
+			client := newClient(t, &fakeAuth{}, ssogrpc.Options{
+				Timeout: time.Second,
+				TLS:     store.ClientConfig(tc.serverName),
+			}, serverCreds)
+
+			isAdmin, err := client.IsAdmin(context.Background(), 1)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, isAdmin)
+		})
+	}
+}
+
+func TestParseCodes(t *testing.T) {
+	got, err := ssogrpc.ParseCodes([]string{"Unavailable", "deadlineexceeded", " Aborted "})
+	require.NoError(t, err)
+	require.Equal(t, []codes.Code{codes.Unavailable, codes.DeadlineExceeded, codes.Aborted}, got)
+
+	_, err = ssogrpc.ParseCodes([]string{"Unavailable", "Sometimes"})
+	require.Error(t, err)
+}
@@ -0,0 +1,6 @@
+package sso
+
+import "errors"
+
+// ErrUnavailable - SSO не настроен или не отвечает. Вызывающий может работать в деградированном режиме
+var ErrUnavailable = errors.New("sso is unavailable")
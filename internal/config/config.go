@@ -1,7 +1,9 @@
 package config
 
 import (
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"time"
 
@@ -16,8 +18,23 @@ type Config struct {
 	Env         string `yaml:"env" env:"ENV" env-default:"local"`
 	StoragePath string `yaml:"storage_path" env-required:"true"`
 	HTTPServer  `yaml:"http_server"`
-	Clients     ClientsConfig `yaml:"clients"`
-	AppSecret   string        `yaml:"app_secret" env-required:"true" env:"APP_SECRET"`
+	Clients     ClientsConfig    `yaml:"clients"`
+	AppSecret   string           `yaml:"app_secret" env-required:"true" env:"APP_SECRET"`
+	RateLimit   RateLimitConfig  `yaml:"rate_limit"`
+	Quotas      map[string]Quota `yaml:"quotas"` // ключ - роль пользователя
+	URLPolicy   URLPolicy        `yaml:"url_policy"`
+	Trash       Trash            `yaml:"trash"`
+	Aliases     Aliases          `yaml:"aliases"`
+	Health      Health           `yaml:"health"`
+	Metrics     Metrics          `yaml:"metrics"`
+	Tracing     Tracing          `yaml:"tracing"`
+	LogLevel    string           `yaml:"log_level" env:"LOG_LEVEL"`       // debug, info, warn, error. Пусто - по env
+	LogLevelTTL time.Duration    `yaml:"log_level_ttl" env-default:"15m"` // на сколько /admin/log-level меняет уровень по умолчанию
+	LogRedact   LogRedact        `yaml:"log_redact"`
+	LogSampling LogSampling      `yaml:"log_sampling"`
+	LogFile     LogFile          `yaml:"log_file"`
+	AccessLog   AccessLog        `yaml:"access_log"`
+	Reload      Reload           `yaml:"reload"`
 }
 
 type HTTPServer struct {
@@ -26,37 +43,268 @@ type HTTPServer struct {
 	IdleTimeout time.Duration `yaml:"idle_timeout" env-default:"60s"`
 	User        string        `yaml:"user" env-required:"true"`
 	Password    string        `yaml:"password" env-required:"true" env:"HTTP_SERVER_PASSWORD"`
+	UID         int64         `yaml:"uid"`          // id основного пользователя в SSO, нужен для админских ручек
+	Users       []User        `yaml:"users"`        // дополнительные пользователи, основной (User) всегда admin
+	PublicHosts []string      `yaml:"public_hosts"` // хосты, на которых доступны короткие ссылки
+
+	// сколько ждем завершения текущих запросов при остановке
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"10s"`
+	// сколько /readyz отвечает 503 перед остановкой, чтобы балансировщик успел убрать инстанс. 0 - сразу
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env-default:"5s"`
+
+	TLS ServerTLS `yaml:"tls"`
+}
+
+// ServerTLS - HTTPS на http_server.address. Пустые cert_path и key_path - обычный HTTP.
+// Сертификат перечитывается при изменении файлов
+type ServerTLS struct {
+	CertPath       string        `yaml:"cert_path"`
+	KeyPath        string        `yaml:"key_path"`
+	MinVersion     string        `yaml:"min_version" env-default:"1.2"` // 1.2 или 1.3
+	CipherSuites   []string      `yaml:"cipher_suites"`                 // для TLS 1.2, пусто - наборы Go по умолчанию
+	HTTP2          bool          `yaml:"http2" env-default:"true"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
+	// обычный HTTP listener, который перенаправляет на HTTPS. Пусто - не нужен
+	RedirectAddress string `yaml:"redirect_address"`
+}
+
+type User struct {
+	Name     string `yaml:"name"`
+	Password string `yaml:"password"`
+	Role     string `yaml:"role"` // по умолчанию user
+	UID      int64  `yaml:"uid"`  // id в SSO, нужен для админских ручек
+}
+
+// URLPolicy - на какие url можно делать короткие ссылки
+type URLPolicy struct {
+	Schemes        []string      `yaml:"schemes" env-default:"http,https"`
+	BlocklistPath  string        `yaml:"blocklist_path"`
+	AllowlistPath  string        `yaml:"allowlist_path"`                    // если задан, разрешены только домены из списка
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"30s"` // как часто проверять изменение файлов
+
+	// запрет ссылок на внутреннюю сеть: приватные, loopback, link-local адреса и хосты самого сервиса
+	BlockPrivate      bool          `yaml:"block_private" env-default:"true"`
+	Resolve           bool          `yaml:"resolve"` // резолвить домен при сохранении, иначе проверяются только ip в url
+	ResolveTimeout    time.Duration `yaml:"resolve_timeout" env-default:"2s"`
+	ServiceHosts      []string      `yaml:"service_hosts"`      // http_server.address добавляется автоматически
+	InternalAllowlist []string      `yaml:"internal_allowlist"` // домены, ip и подсети, на которые ссылки все же разрешены
+
+	// ссылки на наши же короткие ссылки (http_server.public_hosts): allow, flatten или reject
+	ChainMode     string `yaml:"chain_mode" env-default:"flatten"`
+	MaxChainDepth int    `yaml:"max_chain_depth" env-default:"5"`
+}
+
+// Trash - удаленные ссылки хранятся retention, потом удаляются окончательно. retention = 0 - не удаляются
+type Trash struct {
+	Retention     time.Duration `yaml:"retention" env-default:"720h"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env-default:"1h"`
+}
+
+// Aliases - какие alias нельзя занять. Первые сегменты путей роутера резервируются автоматически
+type Aliases struct {
+	Reserved        []string `yaml:"reserved"`
+	BannedWordsPath string   `yaml:"banned_words_path"` // alias не может содержать слова из файла
+	// ABC123 и abc123 - один alias
+	CaseInsensitive bool `yaml:"case_insensitive"`
+	// если при включении case_insensitive существующие alias совпали: fail - сервис не запускается,
+	// keep_first - alias остается у самой старой ссылки, rename - остальные переименовываются в alias-2, alias-3
+	OnConflict string `yaml:"on_conflict" env-default:"fail"`
+}
+
+// Health - проверки для /readyz
+type Health struct {
+	CheckSSO bool          `yaml:"check_sso"` // не готовы, если SSO недоступен. Редиректам SSO не нужен, поэтому по умолчанию выключено
+	Timeout  time.Duration `yaml:"timeout" env-default:"2s"`
+}
+
+// Metrics - prometheus /metrics
+type Metrics struct {
+	Enabled bool   `yaml:"enabled" env-default:"true"`
+	Address string `yaml:"address"` // отдельный админский listener, пусто - /metrics на основном сервере
+}
+
+// Tracing - OpenTelemetry
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" env-default:"none"` // none, otlp, stdout
+	Endpoint    string  `yaml:"endpoint" env-default:"localhost:4317"`
+	Insecure    bool    `yaml:"insecure"`
+	FilePath    string  `yaml:"file_path"` // для stdout: писать в файл
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+}
+
+// LogRedact - что скрывать в логах: пароли, токены в url и тд
+type LogRedact struct {
+	// атрибуты и поля структур, значения которых скрываются целиком
+	Keys []string `yaml:"keys" env-default:"password,secret,token,app_secret,authorization"`
+	// параметры query в url внутри строк
+	QueryParams []string `yaml:"query_params" env-default:"token,access_token,refresh_token,api_key,key,sig,signature"`
+	// регулярные выражения, совпадения с которыми скрываются в любых строках
+	Patterns []string `yaml:"patterns"`
+}
+
+// LogSampling - для частых записей (редиректы): первые first с одним сообщением за tick, потом 1 из thereafter.
+// Ошибки пишутся всегда
+type LogSampling struct {
+	First      int           `yaml:"first"`      // 0 - без сэмплирования
+	Thereafter int           `yaml:"thereafter"` // 0 - после first отбрасываем
+	Tick       time.Duration `yaml:"tick" env-default:"1s"`
+}
+
+// LogFile - логи в файл с ротацией вместо stdout
+type LogFile struct {
+	Path           string        `yaml:"path"` // пусто - stdout
+	MaxSizeMB      int           `yaml:"max_size_mb" env-default:"100"`
+	RotateInterval time.Duration `yaml:"rotate_interval"`             // 24h - раз в сутки, 0 - только по размеру
+	MaxBackups     int           `yaml:"max_backups" env-default:"7"` // 0 - хранить все
+	Compress       bool          `yaml:"compress" env-default:"true"`
+}
+
+// AccessLog - лог запросов. slog пишется вместе с остальными логами (или в свой file),
+// combined (Apache) и ecs (Elastic Common Schema) - построчно в file или stdout, без сэмплирования
+type AccessLog struct {
+	Format string  `yaml:"format" env-default:"slog"` // slog, combined, ecs
+	File   LogFile `yaml:"file"`                      // пустой path - общий лог для slog и stdout для остальных
+}
+
+// Reload - перечитывание конфига без рестарта: по SIGHUP и при изменении файла
+type Reload struct {
+	WatchInterval time.Duration `yaml:"watch_interval"` // как часто проверять файл, 0 - только по SIGHUP
+}
+
+// Quota - ограничения на количество ссылок. 0 - без ограничений
+type Quota struct {
+	MaxActive int `yaml:"max_active"`
+	MaxPerDay int `yaml:"max_per_day"`
 }
 
 type Client struct {
-	Address      string        `yaml:"address"`
+	Address      string        `yaml:"address"` // пусто - клиент выключен
 	Timeout      time.Duration `yaml:"timeout"`
 	RetriesCount int           `yaml:"retriesCount"`
-	//Insecure     bool          `yaml:"insecure"`
+	Insecure     bool          `yaml:"insecure"` // без TLS, только для локальной разработки
+	TLS          ClientTLS     `yaml:"tls"`
+
+	// при каких кодах повторяем запрос, названия как в google.golang.org/grpc/codes
+	RetryCodes   []string      `yaml:"retry_codes" env-default:"Unavailable,Aborted,DeadlineExceeded"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" env-default:"100ms"` // пауза перед первым повтором, дальше x2
+	RetryJitter  float64       `yaml:"retry_jitter" env-default:"0.2"`    // случайное отклонение паузы, доля
+
+	// circuit breaker: после breaker_failures неудач подряд не ходим в SSO breaker_timeout. 0 - выключен
+	BreakerFailures int           `yaml:"breaker_failures" env-default:"5"`
+	BreakerTimeout  time.Duration `yaml:"breaker_timeout" env-default:"30s"`
+}
+
+// ClientTLS - TLS до сервиса. Файлы перечитываются при изменении
+type ClientTLS struct {
+	CAPath         string        `yaml:"ca_path"`     // CA сервера, пусто - системные корневые сертификаты
+	CertPath       string        `yaml:"cert_path"`   // сертификат клиента для mTLS
+	KeyPath        string        `yaml:"key_path"`    // ключ клиента для mTLS
+	ServerName     string        `yaml:"server_name"` // имя в сертификате сервера, если не совпадает с address
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
 }
 
 type ClientsConfig struct {
 	SSO Client `yaml:"sso"`
 }
 
+// RateLimit - token bucket: rps токенов в секунду, не больше burst подряд. rps = 0 - без ограничений
+type RateLimit struct {
+	RPS   float64 `yaml:"rps"`
+	Burst int     `yaml:"burst"`
+}
+
+type RateLimitConfig struct {
+	URL      RateLimit `yaml:"url"`      // создание/удаление ссылок, лимит на пользователя
+	Redirect RateLimit `yaml:"redirect"` // редиректы, лимит на ip
+}
+
+// PathEnv - переменная окружения с путем к конфигу
+const PathEnv = "CONFIG_PATH"
+
 // "Must" - сообщаем, что функция может кинуть панику
 func MustLoad() *Config {
 	// берем путь к конфигу из переменной окружения
-	configPath := os.Getenv("CONFIG_PATH")
+	configPath := os.Getenv(PathEnv)
 	if configPath == "" {
 		log.Fatal("CONFIG_PATH is not set")
 	}
 
+	cfg, err := Load(configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return cfg
+}
+
+// Load читает и проверяет конфиг. Ошибки проверки - все разом, по одной на строку
+func Load(configPath string) (*Config, error) {
 	// check is file exist
 	if _, err := os.Stat(configPath); err != nil {
-		log.Fatalf("config file is not exist, %s", configPath)
+		return nil, fmt.Errorf("config file is not exist, %s", configPath)
 	}
 
 	var cfg Config
 
 	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
-		log.Fatalf("can't read config, %s", err)
+		return nil, fmt.Errorf("can't read config, %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s:\n%w", configPath, err)
+	}
+
+	return &cfg, nil
+}
+
+// Level - уровень логов: log_level, а если не задан - по окружению
+func (c *Config) Level() slog.Level {
+	var level slog.Level
+	if c.LogLevel != "" && level.UnmarshalText([]byte(c.LogLevel)) == nil {
+		return level
+	}
+
+	if c.Env == EnvProd {
+		return slog.LevelInfo
+	}
+
+	return slog.LevelDebug
+}
+
+const redacted = "***"
+
+// String - конфиг без секретов, можно писать в лог
+func (c *Config) String() string {
+	// plain без метода String, иначе fmt уйдет в рекурсию
+	type plain Config
+
+	return fmt.Sprintf("%+v", plain(c.redacted()))
+}
+
+// redacted - копия конфига со скрытыми секретами
+func (c *Config) redacted() Config {
+	cp := *c
+
+	cp.AppSecret = redact(cp.AppSecret)
+	cp.HTTPServer.Password = redact(cp.HTTPServer.Password)
+	cp.HTTPServer.Users = make([]User, len(c.HTTPServer.Users))
+	for i, u := range c.HTTPServer.Users {
+		u.Password = redact(u.Password)
+		cp.HTTPServer.Users[i] = u
+	}
+
+	return cp
+}
+
+// LogValue - для slog.Any: JSON handler иначе сериализует структуру вместе с секретами
+func (c *Config) LogValue() slog.Value {
+	return slog.StringValue(c.String())
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
 	}
 
-	return &cfg
+	return redacted
 }
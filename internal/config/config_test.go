@@ -0,0 +1,228 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+)
+
+func validConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	return &config.Config{
+		Env:         config.EnvLocal,
+		StoragePath: filepath.Join(t.TempDir(), "storage.db"),
+		HTTPServer: config.HTTPServer{
+			Address:         "localhost:8080",
+			Timeout:         4 * time.Second,
+			IdleTimeout:     time.Minute,
+			User:            "admin",
+			Password:        "qwerty",
+			Users:           []config.User{{Name: "bob", Password: "secret"}},
+			ShutdownTimeout: 10 * time.Second,
+		},
+		AppSecret: "app-secret",
+		RateLimit: config.RateLimitConfig{
+			URL: config.RateLimit{RPS: 5, Burst: 20},
+		},
+		URLPolicy: config.URLPolicy{
+			Schemes:       []string{"http", "https"},
+			ChainMode:     "flatten",
+			MaxChainDepth: 5,
+		},
+		Aliases:     config.Aliases{OnConflict: "fail"},
+		Health:      config.Health{Timeout: 2 * time.Second},
+		Tracing:     config.Tracing{Exporter: "none", SampleRatio: 1},
+		LogLevelTTL: 15 * time.Minute,
+		AccessLog:   config.AccessLog{Format: "slog"},
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cases := []struct {
+		name    string
+		modify  func(c *config.Config)
+		wantErr string // пусто - конфиг валиден
+	}{
+		{
+			name:   "Valid",
+			modify: func(c *config.Config) {},
+		},
+		{
+			name:    "Unknown env",
+			modify:  func(c *config.Config) { c.Env = "production" },
+			wantErr: `env: must be one of local, dev, prod, got "production"`,
+		},
+		{
+			name:    "Storage path is a directory",
+			modify:  func(c *config.Config) { c.StoragePath = filepath.Dir(c.StoragePath) },
+			wantErr: "storage_path:",
+		},
+		{
+			name:    "Storage directory does not exist",
+			modify:  func(c *config.Config) { c.StoragePath = filepath.Join(c.StoragePath, "missing", "storage.db") },
+			wantErr: "storage_path: directory is not writable",
+		},
+		{
+			name:    "Invalid address",
+			modify:  func(c *config.Config) { c.HTTPServer.Address = "localhost" },
+			wantErr: "http_server.address: invalid address",
+		},
+		{
+			name:    "Negative shutdown delay",
+			modify:  func(c *config.Config) { c.HTTPServer.ShutdownDelay = -time.Second },
+			wantErr: "http_server.shutdown_delay: must not be negative, got -1s",
+		},
+		{
+			name:    "Invalid port",
+			modify:  func(c *config.Config) { c.HTTPServer.Address = "localhost:80800" },
+			wantErr: "http_server.address: invalid port",
+		},
+		{
+			name:    "Zero timeout",
+			modify:  func(c *config.Config) { c.HTTPServer.Timeout = 0 },
+			wantErr: "http_server.timeout: must be positive",
+		},
+		{
+			name: "Duplicate user",
+			modify: func(c *config.Config) {
+				c.HTTPServer.Users = append(c.HTTPServer.Users, config.User{Name: "admin", Password: "x"})
+			},
+			wantErr: `http_server.users[1].name: duplicate user "admin"`,
+		},
+		{
+			name: "TLS without key",
+			modify: func(c *config.Config) {
+				c.HTTPServer.TLS = config.ServerTLS{CertPath: "cert.pem", MinVersion: "1.2"}
+			},
+			wantErr: "http_server.tls: cert_path and key_path must be set together",
+		},
+		{
+			name: "Unknown TLS version",
+			modify: func(c *config.Config) {
+				c.HTTPServer.TLS = config.ServerTLS{CertPath: "cert.pem", KeyPath: "key.pem", MinVersion: "1.1"}
+			},
+			wantErr: "http_server.tls.min_version:",
+		},
+		{
+			name: "SSO without timeout",
+			modify: func(c *config.Config) {
+				c.Clients.SSO = config.Client{Address: "localhost:44044", Insecure: true}
+			},
+			wantErr: "clients.sso.timeout: must be positive",
+		},
+		{
+			name:    "Rate limit without burst",
+			modify:  func(c *config.Config) { c.RateLimit.Redirect = config.RateLimit{RPS: 10} },
+			wantErr: "rate_limit.redirect.burst: must be positive when rps is set",
+		},
+		{
+			name:    "Unknown chain mode",
+			modify:  func(c *config.Config) { c.URLPolicy.ChainMode = "follow" },
+			wantErr: "url_policy.chain_mode:",
+		},
+		{
+			name:    "Metrics on the main address",
+			modify:  func(c *config.Config) { c.Metrics = config.Metrics{Enabled: true, Address: c.HTTPServer.Address} },
+			wantErr: "metrics.address: must differ from http_server.address",
+		},
+		{
+			name:    "Sample ratio out of range",
+			modify:  func(c *config.Config) { c.Tracing.SampleRatio = 10 },
+			wantErr: "tracing.sample_ratio: must be between 0 and 1",
+		},
+		{
+			name:    "Sampling without tick",
+			modify:  func(c *config.Config) { c.LogSampling = config.LogSampling{First: 10} },
+			wantErr: "log_sampling.tick: must be positive",
+		},
+		{
+			name:    "Unknown alias conflict resolution",
+			modify:  func(c *config.Config) { c.Aliases.OnConflict = "skip" },
+			wantErr: `aliases.on_conflict: must be one of keep_first, rename, fail, got "skip"`,
+		},
+		{
+			name:    "Unknown access log format",
+			modify:  func(c *config.Config) { c.AccessLog.Format = "common" },
+			wantErr: `access_log.format: must be one of slog, combined, ecs, got "common"`,
+		},
+		{
+			name: "Access log in the main log file",
+			modify: func(c *config.Config) {
+				c.LogFile.Path = "/var/log/app.log"
+				c.AccessLog.File.Path = "/var/log/./app.log"
+			},
+			wantErr: "access_log.file.path: must differ from log_file.path",
+		},
+		{
+			name:    "Invalid redact pattern",
+			modify:  func(c *config.Config) { c.LogRedact.Patterns = []string{`\d+`, "("} },
+			wantErr: "log_redact.patterns[1]: invalid regexp",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := validConfig(t)
+			tc.modify(cfg)
+
+			err := cfg.Validate()
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestConfig_Validate_AllErrors(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.Env = "production"
+	cfg.HTTPServer.Timeout = 0
+	cfg.Health.Timeout = -time.Second
+
+	err := cfg.Validate()
+	require.Error(t, err)
+
+	// ошибки не обрываются на первой, каждая на своей строке
+	lines := strings.Split(err.Error(), "\n")
+	require.Len(t, lines, 3)
+
+	var joined interface{ Unwrap() []error }
+	require.True(t, errors.As(err, &joined))
+	require.Len(t, joined.Unwrap(), 3)
+}
+
+func TestConfig_String(t *testing.T) {
+	cfg := validConfig(t)
+
+	s := cfg.String()
+	require.NotContains(t, s, "qwerty")
+	require.NotContains(t, s, "app-secret")
+	require.NotContains(t, s, "Password:secret")
+	require.Contains(t, s, "localhost:8080")
+
+	// исходный конфиг не изменился
+	require.Equal(t, "qwerty", cfg.HTTPServer.Password)
+	require.Equal(t, "secret", cfg.HTTPServer.Users[0].Password)
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("config loaded", slog.Any("config", cfg))
+	require.NotContains(t, buf.String(), "qwerty")
+	require.NotContains(t, buf.String(), "app-secret")
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// reloadable - поля, которые применяются без рестарта. Остальные изменения только логируются
+var reloadable = []string{
+	"log_level",
+	"rate_limit",
+	"url_policy.schemes",
+	"url_policy.blocklist_path",
+	"url_policy.allowlist_path",
+	"http_server.user",
+	"http_server.password",
+	"http_server.uid",
+	"http_server.users",
+}
+
+// Change - изменение одного поля конфига. Значения без секретов
+type Change struct {
+	Field string // путь как в yaml: rate_limit.url.rps
+	Old   string
+	New   string
+}
+
+// Reloadable - применяется ли изменение без рестарта
+func (c Change) Reloadable() bool {
+	for _, field := range reloadable {
+		if c.Field == field || strings.HasPrefix(c.Field, field+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Diff - какие поля отличаются в new по сравнению с old
+func Diff(old *Config, new *Config) []Change {
+	var changes []Change
+
+	// сравниваем настоящие значения, а показываем без секретов: смена пароля видна, сам пароль нет
+	oldShown, newShown := old.redacted(), new.redacted()
+	diff("", reflect.ValueOf(*old), reflect.ValueOf(*new), reflect.ValueOf(oldShown), reflect.ValueOf(newShown), &changes)
+
+	return changes
+}
+
+func diff(path string, old, new, oldShown, newShown reflect.Value, changes *[]Change) {
+	if old.Kind() == reflect.Struct {
+		t := old.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := yamlName(t.Field(i))
+			if path != "" {
+				field = path + "." + field
+			}
+
+			diff(field, old.Field(i), new.Field(i), oldShown.Field(i), newShown.Field(i), changes)
+		}
+
+		return
+	}
+
+	if reflect.DeepEqual(old.Interface(), new.Interface()) {
+		return
+	}
+
+	*changes = append(*changes, Change{
+		Field: path,
+		Old:   fmt.Sprint(oldShown.Interface()),
+		New:   fmt.Sprint(newShown.Interface()),
+	})
+}
+
+func yamlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	if name == "" {
+		return f.Name
+	}
+
+	return name
+}
+
+// withReloadable - running, в котором reloadable поля взяты из loaded
+func withReloadable(running *Config, loaded *Config) *Config {
+	cfg := *running
+	merge("", reflect.ValueOf(&cfg).Elem(), reflect.ValueOf(*loaded))
+
+	return &cfg
+}
+
+func merge(path string, dst, src reflect.Value) {
+	if path != "" && (Change{Field: path}).Reloadable() {
+		dst.Set(src)
+		return
+	}
+
+	if dst.Kind() != reflect.Struct {
+		return
+	}
+
+	t := dst.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := yamlName(t.Field(i))
+		if path != "" {
+			field = path + "." + field
+		}
+
+		merge(field, dst.Field(i), src.Field(i))
+	}
+}
+
+// ApplyFunc применяет перечитанный конфиг к работающим компонентам.
+// В cfg только reloadable поля новые, остальные - как при запуске.
+// Ошибка - ничего не применено, остается старый конфиг
+type ApplyFunc func(cfg *Config) error
+
+// Reloader перечитывает конфиг по SIGHUP и при изменении файла.
+// Невалидный конфиг не применяется, работаем со старым
+type Reloader struct {
+	log   *slog.Logger
+	path  string
+	apply ApplyFunc
+
+	mu      sync.Mutex
+	current *Config
+	modTime time.Time
+}
+
+func NewReloader(log *slog.Logger, path string, current *Config, apply ApplyFunc) *Reloader {
+	r := &Reloader{
+		log:     log.With(slog.String("component", "config")),
+		path:    path,
+		apply:   apply,
+		current: current,
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		r.modTime = info.ModTime()
+	}
+
+	return r
+}
+
+// Reload перечитывает файл и применяет изменения
+func (r *Reloader) Reload() error {
+	const op = "config.Reload"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// запоминаем и для битого файла, чтобы не перечитывать его до следующего изменения
+	if info, err := os.Stat(r.path); err == nil {
+		r.modTime = info.ModTime()
+	}
+
+	cfg, err := Load(r.path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	changes := Diff(r.current, cfg)
+	if len(changes) == 0 {
+		r.log.Info("config is not changed")
+		return nil
+	}
+
+	// остальные поля остаются как при запуске: предупреждение о них повторяется, пока сервис не перезапущен
+	cfg = withReloadable(r.current, cfg)
+
+	if err := r.apply(cfg); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for _, c := range changes {
+		attrs := []any{slog.String("field", c.Field), slog.String("old", c.Old), slog.String("new", c.New)}
+		if c.Reloadable() {
+			r.log.Info("config field changed", attrs...)
+		} else {
+			r.log.Warn("config field changed, restart is required to apply it", attrs...)
+		}
+	}
+
+	r.current = cfg
+	r.log.Info("config reloaded", slog.Int("changes", len(changes)))
+
+	return nil
+}
+
+// Run перечитывает конфиг по сигналу из hup, а если interval > 0 - еще и при изменении файла
+func (r *Reloader) Run(ctx context.Context, hup <-chan os.Signal, interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reload("signal")
+		case <-tick:
+			if r.changed() {
+				r.reload("file changed")
+			}
+		}
+	}
+}
+
+func (r *Reloader) reload(reason string) {
+	r.log.Info("reloading config", slog.String("reason", reason))
+
+	if err := r.Reload(); err != nil {
+		r.log.Error("failed to reload config, keeping the old one", sl.Err(err))
+	}
+}
+
+func (r *Reloader) changed() bool {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		// пусть Reload залогирует ошибку
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return !info.ModTime().Equal(r.modTime)
+}
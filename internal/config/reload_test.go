@@ -0,0 +1,187 @@
+package config_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/config"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestDiff(t *testing.T) {
+	old := validConfig(t)
+	changed := validConfig(t)
+	changed.StoragePath = old.StoragePath
+	changed.RateLimit.URL.RPS = 10
+	changed.HTTPServer.Password = "new-password"
+	changed.HTTPServer.Timeout = 5 * time.Second
+
+	changes := config.Diff(old, changed)
+
+	require.Equal(t, []config.Change{
+		{Field: "http_server.timeout", Old: "4s", New: "5s"},
+		{Field: "http_server.password", Old: "***", New: "***"},
+		{Field: "rate_limit.url.rps", Old: "5", New: "10"},
+	}, changes)
+
+	require.False(t, changes[0].Reloadable())
+	require.True(t, changes[1].Reloadable())
+	require.True(t, changes[2].Reloadable())
+
+	require.Empty(t, config.Diff(old, old))
+}
+
+func writeConfig(t *testing.T, path string, storagePath string, rps int, modTime time.Time) {
+	t.Helper()
+
+	content := fmt.Sprintf(`
+env: local
+storage_path: %q
+http_server:
+  user: admin
+  password: qwerty
+app_secret: secret
+rate_limit:
+  url:
+    rps: %d
+    burst: 10
+`, storagePath, rps)
+
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	storagePath := filepath.Join(dir, "storage.db")
+	writeConfig(t, path, storagePath, 5, time.Now())
+
+	current, err := config.Load(path)
+	require.NoError(t, err)
+
+	var applied []*config.Config
+	applyErr := error(nil)
+	reloader := config.NewReloader(slogdiscard.NewDiscardLogger(), path, current, func(cfg *config.Config) error {
+		if applyErr != nil {
+			return applyErr
+		}
+		applied = append(applied, cfg)
+
+		return nil
+	})
+
+	// ничего не изменилось - применять нечего
+	require.NoError(t, reloader.Reload())
+	require.Empty(t, applied)
+
+	writeConfig(t, path, storagePath, 10, time.Now().Add(time.Second))
+	require.NoError(t, reloader.Reload())
+	require.Len(t, applied, 1)
+	require.Equal(t, float64(10), applied[0].RateLimit.URL.RPS)
+
+	// невалидный конфиг не применяется
+	writeConfig(t, path, storagePath, -1, time.Now().Add(2*time.Second))
+	require.Error(t, reloader.Reload())
+	require.Len(t, applied, 1)
+
+	// компонент не принял конфиг - он не становится текущим, следующий Reload пробует снова
+	writeConfig(t, path, storagePath, 20, time.Now().Add(3*time.Second))
+	applyErr = errors.New("broken blocklist")
+	require.Error(t, reloader.Reload())
+	require.Len(t, applied, 1)
+
+	applyErr = nil
+	require.NoError(t, reloader.Reload())
+	require.Len(t, applied, 2)
+	require.Equal(t, float64(20), applied[1].RateLimit.URL.RPS)
+}
+
+func TestReloader_NotReloadable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	storagePath := filepath.Join(dir, "storage.db")
+	writeConfig(t, path, storagePath, 5, time.Now())
+
+	current, err := config.Load(path)
+	require.NoError(t, err)
+
+	var (
+		applied []*config.Config
+		logs    bytes.Buffer
+	)
+	log := slog.New(slog.NewTextHandler(&logs, nil))
+	reloader := config.NewReloader(log, path, current, func(cfg *config.Config) error {
+		applied = append(applied, cfg)
+		return nil
+	})
+
+	// storage_path без рестарта не меняется, rps - меняется
+	writeConfig(t, path, filepath.Join(dir, "other.db"), 10, time.Now().Add(time.Second))
+	require.NoError(t, reloader.Reload())
+	require.Len(t, applied, 1)
+	require.Equal(t, float64(10), applied[0].RateLimit.URL.RPS)
+	require.Equal(t, storagePath, applied[0].StoragePath)
+
+	// пока не перезапустились, storage_path по-прежнему отличается от файла
+	require.NoError(t, reloader.Reload())
+	require.Len(t, applied, 2)
+	require.Equal(t, storagePath, applied[1].StoragePath)
+
+	require.Equal(t, 2, strings.Count(logs.String(), "field=storage_path"))
+	require.Equal(t, 1, strings.Count(logs.String(), "field=rate_limit.url.rps"))
+}
+
+func TestReloader_Run(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	storagePath := filepath.Join(dir, "storage.db")
+	writeConfig(t, path, storagePath, 5, time.Now())
+
+	current, err := config.Load(path)
+	require.NoError(t, err)
+
+	applied := make(chan float64, 2)
+	reloader := config.NewReloader(slogdiscard.NewDiscardLogger(), path, current, func(cfg *config.Config) error {
+		applied <- cfg.RateLimit.URL.RPS
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hup := make(chan os.Signal, 1)
+	go reloader.Run(ctx, hup, 10*time.Millisecond)
+
+	// изменение файла подхватывается само
+	writeConfig(t, path, storagePath, 10, time.Now().Add(time.Second))
+	require.Equal(t, float64(10), waitApplied(t, applied))
+
+	// по сигналу перечитываем, даже если mtime не изменился
+	writeConfig(t, path, storagePath, 20, time.Now().Add(time.Second))
+	hup <- syscall.SIGHUP
+	require.Equal(t, float64(20), waitApplied(t, applied))
+}
+
+func waitApplied(t *testing.T, applied <-chan float64) float64 {
+	t.Helper()
+
+	select {
+	case rps := <-applied:
+		return rps
+	case <-time.After(time.Second):
+		t.Fatal("config is not applied")
+		return 0
+	}
+}
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"url-shortener/internal/lib/certs"
+	"url-shortener/internal/lib/logger/accesslog"
+	"url-shortener/internal/lib/tracing"
+	"url-shortener/internal/lib/urlchain"
+	"url-shortener/internal/storage"
+)
+
+const (
+	EnvLocal = "local"
+	EnvDev   = "dev"
+	EnvProd  = "prod"
+)
+
+// validator собирает все ошибки конфига, чтобы показать их разом, а не по одной на запуск
+type validator struct {
+	errs []error
+}
+
+// fieldf - ошибка поля, field - путь как в yaml: http_server.timeout
+func (v *validator) fieldf(field string, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) check(ok bool, field string, format string, args ...any) {
+	if !ok {
+		v.fieldf(field, format, args...)
+	}
+}
+
+func (v *validator) oneOf(field string, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+
+	v.fieldf(field, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+}
+
+func (v *validator) positive(field string, d time.Duration) {
+	v.check(d > 0, field, "must be positive, got %s", d)
+}
+
+func (v *validator) notNegative(field string, d time.Duration) {
+	v.check(d >= 0, field, "must not be negative, got %s", d)
+}
+
+// address - host:port, host можно не указывать
+func (v *validator) address(field string, addr string) {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		v.fieldf(field, "invalid address %q: %s", addr, err)
+		return
+	}
+
+	if n, err := strconv.Atoi(port); err != nil || n < 0 || n > 65535 {
+		v.fieldf(field, "invalid port in address %q", addr)
+	}
+}
+
+func (v *validator) err() error {
+	return errors.Join(v.errs...)
+}
+
+// Validate проверяет значения конфига по смыслу. cleanenv проверяет только наличие обязательных полей
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.oneOf("env", c.Env, EnvLocal, EnvDev, EnvProd)
+	v.storagePath(c.StoragePath)
+	v.httpServer(c.HTTPServer)
+	v.sso(c.Clients.SSO)
+
+	v.rateLimit("rate_limit.url", c.RateLimit.URL)
+	v.rateLimit("rate_limit.redirect", c.RateLimit.Redirect)
+
+	roles := make([]string, 0, len(c.Quotas))
+	for role := range c.Quotas {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		q := c.Quotas[role]
+		v.check(q.MaxActive >= 0, "quotas."+role+".max_active", "must not be negative")
+		v.check(q.MaxPerDay >= 0, "quotas."+role+".max_per_day", "must not be negative")
+	}
+
+	v.urlPolicy(c.URLPolicy)
+	v.oneOf("aliases.on_conflict", c.Aliases.OnConflict,
+		storage.OnConflictKeepFirst, storage.OnConflictRename, storage.OnConflictFail)
+
+	v.notNegative("trash.retention", c.Trash.Retention)
+	if c.Trash.Retention > 0 {
+		v.positive("trash.purge_interval", c.Trash.PurgeInterval)
+	}
+
+	v.positive("health.timeout", c.Health.Timeout)
+
+	if c.Metrics.Enabled && c.Metrics.Address != "" {
+		v.address("metrics.address", c.Metrics.Address)
+		v.check(c.Metrics.Address != c.HTTPServer.Address, "metrics.address", "must differ from http_server.address")
+	}
+
+	v.oneOf("tracing.exporter", c.Tracing.Exporter, tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout)
+	if c.Tracing.Exporter == tracing.ExporterOTLP {
+		v.address("tracing.endpoint", c.Tracing.Endpoint)
+	}
+	v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1,
+		"tracing.sample_ratio", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+
+	if c.LogLevel != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+			v.fieldf("log_level", "must be one of debug, info, warn, error, got %q", c.LogLevel)
+		}
+	}
+	v.positive("log_level_ttl", c.LogLevelTTL)
+	for i, p := range c.LogRedact.Patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			v.fieldf(fmt.Sprintf("log_redact.patterns[%d]", i), "invalid regexp: %s", err)
+		}
+	}
+	v.check(c.LogSampling.First >= 0, "log_sampling.first", "must not be negative")
+	v.check(c.LogSampling.Thereafter >= 0, "log_sampling.thereafter", "must not be negative")
+	if c.LogSampling.First > 0 {
+		v.positive("log_sampling.tick", c.LogSampling.Tick)
+	}
+	v.logFile("log_file", c.LogFile)
+	v.oneOf("access_log.format", c.AccessLog.Format, accesslog.FormatSlog, accesslog.FormatCombined, accesslog.FormatECS)
+	v.logFile("access_log.file", c.AccessLog.File)
+	if c.AccessLog.File.Path != "" {
+		v.check(filepath.Clean(c.AccessLog.File.Path) != filepath.Clean(c.LogFile.Path),
+			"access_log.file.path", "must differ from log_file.path")
+	}
+	v.notNegative("reload.watch_interval", c.Reload.WatchInterval)
+
+	return v.err()
+}
+
+// storagePath - база должна открываться на запись: иначе sqlite упадет только на первом сохранении ссылки
+func (v *validator) storagePath(path string) {
+	const field = "storage_path"
+
+	if path == "" {
+		v.fieldf(field, "must not be empty")
+		return
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && info.IsDir():
+		v.fieldf(field, "%q is a directory", path)
+	case err == nil:
+		f, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			v.fieldf(field, "database is not writable: %s", err)
+			return
+		}
+		_ = f.Close()
+	case errors.Is(err, os.ErrNotExist):
+		// базы еще нет: sqlite создаст ее, если каталог доступен на запись
+		f, err := os.CreateTemp(filepath.Dir(path), ".write-check-*")
+		if err != nil {
+			v.fieldf(field, "directory is not writable: %s", err)
+			return
+		}
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	default:
+		v.fieldf(field, "%s", err)
+	}
+}
+
+func (v *validator) httpServer(s HTTPServer) {
+	v.address("http_server.address", s.Address)
+	v.positive("http_server.timeout", s.Timeout)
+	v.positive("http_server.idle_timeout", s.IdleTimeout)
+	v.positive("http_server.shutdown_timeout", s.ShutdownTimeout)
+	v.notNegative("http_server.shutdown_delay", s.ShutdownDelay)
+
+	names := map[string]struct{}{s.User: {}}
+	for i, u := range s.Users {
+		field := fmt.Sprintf("http_server.users[%d]", i)
+		if u.Name == "" {
+			v.fieldf(field+".name", "must not be empty")
+			continue
+		}
+		if _, ok := names[u.Name]; ok {
+			v.fieldf(field+".name", "duplicate user %q", u.Name)
+		}
+		names[u.Name] = struct{}{}
+
+		v.check(u.Password != "", field+".password", "must not be empty")
+	}
+
+	tls := s.TLS
+	if tls.CertPath == "" && tls.KeyPath == "" {
+		return
+	}
+
+	v.check(tls.CertPath != "" && tls.KeyPath != "", "http_server.tls", "cert_path and key_path must be set together")
+	if _, err := certs.ParseVersion(tls.MinVersion); err != nil {
+		v.fieldf("http_server.tls.min_version", "%s", err)
+	}
+	if _, err := certs.ParseCipherSuites(tls.CipherSuites); err != nil {
+		v.fieldf("http_server.tls.cipher_suites", "%s", err)
+	}
+	v.notNegative("http_server.tls.reload_interval", tls.ReloadInterval)
+	if tls.RedirectAddress != "" {
+		v.address("http_server.tls.redirect_address", tls.RedirectAddress)
+		v.check(tls.RedirectAddress != s.Address, "http_server.tls.redirect_address", "must differ from http_server.address")
+	}
+}
+
+func (v *validator) sso(c Client) {
+	if c.Address == "" {
+		return
+	}
+
+	// gRPC target со схемой (dns:///host:port) проверит сам gRPC
+	if !strings.Contains(c.Address, "://") {
+		v.address("clients.sso.address", c.Address)
+	}
+	v.positive("clients.sso.timeout", c.Timeout)
+	v.check(c.RetriesCount >= 0, "clients.sso.retriesCount", "must not be negative")
+	v.notNegative("clients.sso.retry_backoff", c.RetryBackoff)
+	v.check(c.RetryJitter >= 0 && c.RetryJitter <= 1, "clients.sso.retry_jitter", "must be between 0 and 1, got %v", c.RetryJitter)
+	v.check(c.BreakerFailures >= 0, "clients.sso.breaker_failures", "must not be negative")
+	if c.BreakerFailures > 0 {
+		v.positive("clients.sso.breaker_timeout", c.BreakerTimeout)
+	}
+
+	if !c.Insecure {
+		v.check((c.TLS.CertPath == "") == (c.TLS.KeyPath == ""), "clients.sso.tls", "cert_path and key_path must be set together")
+		v.notNegative("clients.sso.tls.reload_interval", c.TLS.ReloadInterval)
+	}
+}
+
+func (v *validator) rateLimit(field string, l RateLimit) {
+	v.check(l.RPS >= 0, field+".rps", "must not be negative")
+	v.check(l.Burst >= 0, field+".burst", "must not be negative")
+	if l.RPS > 0 {
+		v.check(l.Burst > 0, field+".burst", "must be positive when rps is set")
+	}
+}
+
+func (v *validator) logFile(field string, f LogFile) {
+	if f.Path == "" {
+		return
+	}
+
+	v.check(f.MaxSizeMB >= 0, field+".max_size_mb", "must not be negative")
+	v.notNegative(field+".rotate_interval", f.RotateInterval)
+	v.check(f.MaxBackups >= 0, field+".max_backups", "must not be negative")
+}
+
+func (v *validator) urlPolicy(p URLPolicy) {
+	v.check(len(p.Schemes) > 0, "url_policy.schemes", "must not be empty")
+	v.notNegative("url_policy.reload_interval", p.ReloadInterval)
+	if p.Resolve {
+		v.positive("url_policy.resolve_timeout", p.ResolveTimeout)
+	}
+	v.oneOf("url_policy.chain_mode", p.ChainMode, urlchain.ModeAllow, urlchain.ModeFlatten, urlchain.ModeReject)
+	v.check(p.MaxChainDepth > 0, "url_policy.max_chain_depth", "must be positive")
+}
@@ -0,0 +1,134 @@
+package auditlog
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type AuditGetter interface {
+	AuditEntries(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEntry, error)
+}
+
+type Entry struct {
+	ID        int64     `json:"id"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Alias     string    `json:"alias"`
+	OldValue  string    `json:"old_value,omitempty"`
+	NewValue  string    `json:"new_value,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Response struct {
+	resp.Response
+	Entries []Entry `json:"entries"`
+}
+
+// New - журнал изменений. Фильтры в query: actor, action, alias, since, until (RFC3339), limit, offset
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=AuditGetter
+func New(log *slog.Logger, auditGetter AuditGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.auditlog.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		filter, err := parseFilter(r)
+		if err != nil {
+			log.Info("invalid filter", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(err.Error()))
+
+			return
+		}
+
+		entries, err := auditGetter.AuditEntries(r.Context(), filter)
+		if err != nil {
+			log.Error("failed to get audit entries", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+
+			return
+		}
+
+		res := Response{
+			Response: resp.Ok(),
+			Entries:  make([]Entry, 0, len(entries)),
+		}
+		for _, e := range entries {
+			res.Entries = append(res.Entries, Entry{
+				ID:        e.ID,
+				Actor:     e.Actor,
+				Action:    e.Action,
+				Alias:     e.Alias,
+				OldValue:  e.OldValue,
+				NewValue:  e.NewValue,
+				RequestID: e.RequestID,
+				CreatedAt: e.CreatedAt,
+			})
+		}
+
+		render.JSON(w, r, res)
+	}
+}
+
+func parseFilter(r *http.Request) (storage.AuditFilter, error) {
+	q := r.URL.Query()
+
+	filter := storage.AuditFilter{
+		Actor:  q.Get("actor"),
+		Action: q.Get("action"),
+		Alias:  q.Get("alias"),
+		Limit:  defaultLimit,
+	}
+
+	var err error
+
+	if v := q.Get("since"); v != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, errInvalidParam("since")
+		}
+	}
+
+	if v := q.Get("until"); v != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, errInvalidParam("until")
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 1 || filter.Limit > maxLimit {
+			return filter, errInvalidParam("limit")
+		}
+	}
+
+	if v := q.Get("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil || filter.Offset < 0 {
+			return filter, errInvalidParam("offset")
+		}
+	}
+
+	return filter, nil
+}
+
+func errInvalidParam(name string) error {
+	return fmt.Errorf("invalid parameter %s", name)
+}
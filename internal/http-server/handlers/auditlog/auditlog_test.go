@@ -0,0 +1,113 @@
+package auditlog_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/auditlog"
+	"url-shortener/internal/http-server/handlers/auditlog/mocks"
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestAuditLogHandler(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name      string
+		query     string
+		filter    *storage.AuditFilter // nil - в storage не ходим
+		entries   []storage.AuditEntry
+		mockError error
+		status    int
+		respError string
+	}{
+		{
+			name:   "Success",
+			query:  "",
+			filter: &storage.AuditFilter{Limit: 100},
+			entries: []storage.AuditEntry{
+				{ID: 1, Actor: "admin", Action: audit.ActionCreate, Alias: "abc", NewValue: "https://google.com", CreatedAt: createdAt},
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:  "Filters",
+			query: "?actor=bob&action=delete&alias=abc&since=2024-03-01T00:00:00Z&until=2024-03-02T00:00:00Z&limit=10&offset=20",
+			filter: &storage.AuditFilter{
+				Actor:  "bob",
+				Action: audit.ActionDelete,
+				Alias:  "abc",
+				Since:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				Until:  time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+				Limit:  10,
+				Offset: 20,
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:      "Invalid since",
+			query:     "?since=yesterday",
+			status:    http.StatusBadRequest,
+			respError: "invalid parameter since",
+		},
+		{
+			name:      "Limit too big",
+			query:     "?limit=100000",
+			status:    http.StatusBadRequest,
+			respError: "invalid parameter limit",
+		},
+		{
+			name:      "AuditEntries Error",
+			filter:    &storage.AuditFilter{Limit: 100},
+			mockError: errors.New("unexpected error"),
+			status:    http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			auditGetterMock := mocks.NewAuditGetter(t)
+
+			if tc.filter != nil {
+				auditGetterMock.On("AuditEntries", mock.Anything, *tc.filter).
+					Return(tc.entries, tc.mockError).
+					Once()
+			}
+
+			handler := auditlog.New(slogdiscard.NewDiscardLogger(), auditGetterMock)
+
+			req := httptest.NewRequest(http.MethodGet, "/audit"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var resp auditlog.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Len(t, resp.Entries, len(tc.entries))
+
+			for i, e := range tc.entries {
+				require.Equal(t, e.Alias, resp.Entries[i].Alias)
+				require.Equal(t, e.Action, resp.Entries[i].Action)
+				require.True(t, e.CreatedAt.Equal(resp.Entries[i].CreatedAt))
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package healthz
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// New - liveness: процесс жив и обрабатывает запросы. Зависимости не проверяем,
+// иначе балансировщик будет перезапускать сервис из-за недоступной БД или SSO
+func New() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, resp.Ok())
+	}
+}
@@ -0,0 +1,100 @@
+package readyz
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/render"
+
+	resp "url-shortener/internal/lib/api/response"
+)
+
+type Response struct {
+	resp.Response
+	Checks map[string]string `json:"checks,omitempty"` // зависимость -> ok или failed. Текст ошибки только в логах
+}
+
+// Pinger - зависимость, без которой сервис не готов принимать запросы (storage, SSO)
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+const (
+	checkOK     = "ok"
+	checkFailed = "failed"
+)
+
+// New - readiness: проверяет все зависимости параллельно, каждую не дольше timeout.
+// После начала остановки (shuttingDown) сразу отвечает 503, чтобы балансировщик перестал слать запросы
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=Pinger
+func New(log *slog.Logger, shuttingDown *atomic.Bool, timeout time.Duration, deps map[string]Pinger) http.HandlerFunc {
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.health.readyz.New"
+
+		log := log.With(
+			slog.String("op", op),
+		)
+
+		if shuttingDown.Load() {
+			render.Status(r, http.StatusServiceUnavailable)
+			render.JSON(w, r, Response{Response: resp.Error("shutting down")})
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		var (
+			mu     sync.Mutex
+			wg     sync.WaitGroup
+			checks = make(map[string]string, len(deps))
+			failed = make(map[string]string)
+		)
+
+		for _, name := range names {
+			name := name
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				err := deps[name].Ping(ctx)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				checks[name] = checkOK
+				if err != nil {
+					// /readyz доступен без авторизации, наружу только статус
+					checks[name] = checkFailed
+					failed[name] = err.Error()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(failed) > 0 {
+			log.Warn("service is not ready", slog.Any("failed", failed))
+
+			render.Status(r, http.StatusServiceUnavailable)
+			render.JSON(w, r, Response{Response: resp.Error("not ready"), Checks: checks})
+
+			return
+		}
+
+		render.JSON(w, r, Response{Response: resp.Ok(), Checks: checks})
+	}
+}
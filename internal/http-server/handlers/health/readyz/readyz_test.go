@@ -0,0 +1,94 @@
+package readyz_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/health/readyz"
+	"url-shortener/internal/http-server/handlers/health/readyz/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestReadyzHandler(t *testing.T) {
+	cases := []struct {
+		name         string
+		storageError error
+		ssoError     error
+		shuttingDown bool
+		status       int
+		respError    string
+		checks       map[string]string
+	}{
+		{
+			name:   "Ready",
+			status: http.StatusOK,
+			checks: map[string]string{"storage": "ok", "sso": "ok"},
+		},
+		{
+			name:      "SSO unavailable",
+			ssoError:  errors.New("sso is unavailable"),
+			status:    http.StatusServiceUnavailable,
+			respError: "not ready",
+			checks:    map[string]string{"storage": "ok", "sso": "failed"},
+		},
+		{
+			name:         "Storage unavailable",
+			storageError: errors.New("database is closed"),
+			status:       http.StatusServiceUnavailable,
+			respError:    "not ready",
+			checks:       map[string]string{"storage": "failed", "sso": "ok"},
+		},
+		{
+			name:         "Shutting down",
+			shuttingDown: true,
+			status:       http.StatusServiceUnavailable,
+			respError:    "shutting down",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			storageMock := mocks.NewPinger(t)
+			ssoMock := mocks.NewPinger(t)
+
+			if !tc.shuttingDown {
+				storageMock.On("Ping", mock.Anything).Return(tc.storageError).Once()
+				ssoMock.On("Ping", mock.Anything).Return(tc.ssoError).Once()
+			}
+
+			var shuttingDown atomic.Bool
+			shuttingDown.Store(tc.shuttingDown)
+
+			handler := readyz.New(
+				slogdiscard.NewDiscardLogger(),
+				&shuttingDown,
+				time.Second,
+				map[string]readyz.Pinger{"storage": storageMock, "sso": ssoMock},
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var resp readyz.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.checks, resp.Checks)
+		})
+	}
+}
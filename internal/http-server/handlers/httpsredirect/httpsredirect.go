@@ -0,0 +1,40 @@
+package httpsredirect
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// New перенаправляет запросы по HTTP на тот же хост и путь по HTTPS.
+// httpsAddr - адрес HTTPS сервера: его порт добавляется к хосту, если он не 443.
+// 308, а не 301, чтобы POST и DELETE не превратились в GET
+func New(httpsAddr string) http.HandlerFunc {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	if port == "443" {
+		port = ""
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		if port != "" {
+			host = net.JoinHostPort(host, port)
+		} else if ip := net.ParseIP(host); ip != nil && ip.To4() == nil {
+			host = "[" + host + "]"
+		}
+
+		target := url.URL{
+			Scheme:   "https",
+			Host:     host,
+			Path:     r.URL.Path,
+			RawPath:  r.URL.RawPath,
+			RawQuery: r.URL.RawQuery,
+		}
+
+		http.Redirect(w, r, target.String(), http.StatusPermanentRedirect)
+	}
+}
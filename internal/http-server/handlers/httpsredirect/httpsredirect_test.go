@@ -0,0 +1,80 @@
+package httpsredirect_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/httpsredirect"
+)
+
+func TestHTTPSRedirect(t *testing.T) {
+	cases := []struct {
+		name      string
+		httpsAddr string
+		method    string
+		target    string
+		host      string
+		want      string
+	}{
+		{
+			name:      "Default port",
+			httpsAddr: ":443",
+			method:    http.MethodGet,
+			target:    "/abc?utm=1",
+			host:      "sho.rt",
+			want:      "https://sho.rt/abc?utm=1",
+		},
+		{
+			name:      "Custom port",
+			httpsAddr: "0.0.0.0:8443",
+			method:    http.MethodGet,
+			target:    "/abc",
+			host:      "sho.rt:8080",
+			want:      "https://sho.rt:8443/abc",
+		},
+		{
+			name:      "Port of http listener is dropped",
+			httpsAddr: ":443",
+			method:    http.MethodGet,
+			target:    "/",
+			host:      "sho.rt:80",
+			want:      "https://sho.rt/",
+		},
+		{
+			name:      "IPv6",
+			httpsAddr: ":443",
+			method:    http.MethodGet,
+			target:    "/abc",
+			host:      "[::1]:80",
+			want:      "https://[::1]/abc",
+		},
+		{
+			name:      "POST keeps method",
+			httpsAddr: ":443",
+			method:    http.MethodPost,
+			target:    "/url",
+			host:      "sho.rt",
+			want:      "https://sho.rt/url",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tc.method, tc.target, nil)
+			req.Host = tc.host
+			rr := httptest.NewRecorder()
+
+			httpsredirect.New(tc.httpsAddr).ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusPermanentRedirect, rr.Code)
+			require.Equal(t, tc.want, rr.Header().Get("Location"))
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package loglevel
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/sloglevel"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+// maxTTL - дольше временный уровень не держим: про debug на проде легко забыть
+const maxTTL = 24 * time.Hour
+
+// Levels - уровни логов сервиса и компонентов
+type Levels interface {
+	Set(component string, level slog.Level, ttl time.Duration)
+	Reset(component string)
+	Base() slog.Level
+	Overrides() []sloglevel.Override
+}
+
+type Request struct {
+	Level     string `json:"level"`               // debug, info, warn, error
+	Component string `json:"component,omitempty"` // например middleware/logger, пусто - весь сервис
+	TTL       string `json:"ttl,omitempty"`       // через сколько вернуть уровень из конфига, например 30m
+}
+
+type Response struct {
+	resp.Response
+	Level     slog.Level           `json:"level"` // из конфига
+	Overrides []sloglevel.Override `json:"overrides"`
+}
+
+// NewGet - уровень из конфига и действующие временные уровни
+func NewGet(levels Levels) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseOk(w, r, levels)
+	}
+}
+
+// New временно меняет уровень логов всего сервиса или одного компонента.
+// Через ttl (по умолчанию defaultTTL) уровень сам возвращается к уровню из конфига
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=Levels
+func New(log *slog.Logger, levels Levels, defaultTTL time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.loglevel.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error("failed to decode request"))
+
+			return
+		}
+
+		level, ttl, err := parseRequest(req, defaultTTL)
+		if err != nil {
+			log.Info("invalid request", sl.Err(err))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, resp.Error(err.Error()))
+
+			return
+		}
+
+		levels.Set(req.Component, level, ttl)
+
+		// Warn, чтобы запись была видна при любом уровне
+		log.Warn("log level changed",
+			slog.String("component", req.Component),
+			slog.String("level", level.String()),
+			slog.Duration("ttl", ttl),
+		)
+
+		responseOk(w, r, levels)
+	}
+}
+
+// NewReset возвращает уровень из конфига раньше времени. Компонент - в query: ?component=middleware/logger
+func NewReset(log *slog.Logger, levels Levels) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.loglevel.NewReset"
+
+		component := r.URL.Query().Get("component")
+		levels.Reset(component)
+
+		log.Warn("log level reset",
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			slog.String("component", component),
+		)
+
+		responseOk(w, r, levels)
+	}
+}
+
+func parseRequest(req Request, defaultTTL time.Duration) (slog.Level, time.Duration, error) {
+	var level slog.Level
+	switch strings.ToLower(req.Level) {
+	case "debug":
+		level = slog.LevelDebug
+	case "info":
+		level = slog.LevelInfo
+	case "warn":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	case "":
+		return 0, 0, errors.New("field level is a required field")
+	default:
+		return 0, 0, fmt.Errorf("field level must be one of debug, info, warn, error")
+	}
+
+	ttl := defaultTTL
+	if req.TTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			return 0, 0, errors.New("field ttl must be a positive duration, for example 30m")
+		}
+	}
+	if ttl > maxTTL {
+		return 0, 0, fmt.Errorf("field ttl must be at most %s", maxTTL)
+	}
+
+	return level, ttl, nil
+}
+
+func responseOk(w http.ResponseWriter, r *http.Request, levels Levels) {
+	render.JSON(w, r, Response{
+		Response:  resp.Ok(),
+		Level:     levels.Base(),
+		Overrides: levels.Overrides(),
+	})
+}
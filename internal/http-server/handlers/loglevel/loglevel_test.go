@@ -0,0 +1,147 @@
+package loglevel_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/loglevel"
+	"url-shortener/internal/http-server/handlers/loglevel/mocks"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/logger/handlers/sloglevel"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		component string
+		level     slog.Level
+		ttl       time.Duration // 0 - Set не вызывается
+		status    int
+		respError string
+	}{
+		{
+			name:      "Component",
+			body:      `{"level":"debug","component":"middleware/logger","ttl":"30m"}`,
+			component: "middleware/logger",
+			level:     slog.LevelDebug,
+			ttl:       30 * time.Minute,
+			status:    http.StatusOK,
+		},
+		{
+			name:   "Whole service with default ttl",
+			body:   `{"level":"WARN"}`,
+			level:  slog.LevelWarn,
+			ttl:    15 * time.Minute,
+			status: http.StatusOK,
+		},
+		{
+			name:      "Empty level",
+			body:      `{"component":"trash"}`,
+			status:    http.StatusBadRequest,
+			respError: "field level is a required field",
+		},
+		{
+			name:      "Unknown level",
+			body:      `{"level":"trace"}`,
+			status:    http.StatusBadRequest,
+			respError: "field level must be one of debug, info, warn, error",
+		},
+		{
+			name:      "Invalid ttl",
+			body:      `{"level":"debug","ttl":"soon"}`,
+			status:    http.StatusBadRequest,
+			respError: "field ttl must be a positive duration, for example 30m",
+		},
+		{
+			name:      "Too long ttl",
+			body:      `{"level":"debug","ttl":"48h"}`,
+			status:    http.StatusBadRequest,
+			respError: "field ttl must be at most 24h0m0s",
+		},
+		{
+			name:      "Invalid body",
+			body:      `{"level":`,
+			status:    http.StatusBadRequest,
+			respError: "failed to decode request",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			levelsMock := mocks.NewLevels(t)
+			if tc.ttl != 0 {
+				levelsMock.On("Set", tc.component, tc.level, tc.ttl).Once()
+				levelsMock.On("Base").Return(slog.LevelInfo).Once()
+				levelsMock.On("Overrides").Return([]sloglevel.Override{
+					{Component: tc.component, Level: tc.level},
+				}).Once()
+			}
+
+			handler := loglevel.New(slogdiscard.NewDiscardLogger(), levelsMock, 15*time.Minute)
+
+			req := httptest.NewRequest(http.MethodPut, "/admin/log-level", bytes.NewReader([]byte(tc.body)))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var resp loglevel.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			require.Equal(t, tc.respError, resp.Error)
+
+			if tc.respError == "" {
+				require.Equal(t, slog.LevelInfo, resp.Level)
+				require.Len(t, resp.Overrides, 1)
+				require.Equal(t, tc.level, resp.Overrides[0].Level)
+			}
+		})
+	}
+}
+
+func TestResetHandler(t *testing.T) {
+	levelsMock := mocks.NewLevels(t)
+	levelsMock.On("Reset", "trash").Once()
+	levelsMock.On("Base").Return(slog.LevelInfo).Once()
+	levelsMock.On("Overrides").Return([]sloglevel.Override{}).Once()
+
+	handler := loglevel.NewReset(slogdiscard.NewDiscardLogger(), levelsMock)
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/log-level?component=trash", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"status":"OK","level":"INFO","overrides":[]}`, rr.Body.String())
+}
+
+func TestGetHandler(t *testing.T) {
+	until := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	levelsMock := mocks.NewLevels(t)
+	levelsMock.On("Base").Return(slog.LevelInfo).Once()
+	levelsMock.On("Overrides").Return([]sloglevel.Override{
+		{Component: "middleware/logger", Level: slog.LevelDebug, Until: until},
+	}).Once()
+
+	rr := httptest.NewRecorder()
+	loglevel.NewGet(levelsMock).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/log-level", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{
+		"status":"OK",
+		"level":"INFO",
+		"overrides":[{"component":"middleware/logger","level":"DEBUG","until":"2024-03-01T12:00:00Z"}]
+	}`, rr.Body.String())
+}
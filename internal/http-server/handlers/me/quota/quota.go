@@ -0,0 +1,71 @@
+package quota
+
+import (
+	"context"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+type UsageGetter interface {
+	QuotaUsage(ctx context.Context, owner string) (storage.QuotaUsage, error)
+}
+
+// Usage - использовано/доступно. Limit = 0 - без ограничений
+type Usage struct {
+	Used  int `json:"used"`
+	Limit int `json:"limit"`
+}
+
+type Response struct {
+	resp.Response
+	User   string `json:"user,omitempty"`
+	Role   string `json:"role,omitempty"`
+	Active *Usage `json:"active,omitempty"`
+	Daily  *Usage `json:"daily,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UsageGetter
+func New(log *slog.Logger, usageGetter UsageGetter, quotas map[string]storage.Quota) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.me.quota.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		user, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			log.Error("user not found in context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, resp.Error("unauthorized"))
+
+			return
+		}
+
+		usage, err := usageGetter.QuotaUsage(r.Context(), user.Name)
+		if err != nil {
+			log.Error("failed to get quota usage", sl.Err(err))
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, resp.Error("internal error"))
+
+			return
+		}
+
+		quota := quotas[user.Role]
+
+		render.JSON(w, r, Response{
+			Response: resp.Ok(),
+			User:     user.Name,
+			Role:     user.Role,
+			Active:   &Usage{Used: usage.Active, Limit: quota.MaxActive},
+			Daily:    &Usage{Used: usage.Today, Limit: quota.MaxPerDay},
+		})
+	}
+}
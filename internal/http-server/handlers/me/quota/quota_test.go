@@ -0,0 +1,104 @@
+package quota_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/me/quota"
+	"url-shortener/internal/http-server/handlers/me/quota/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestQuotaHandler(t *testing.T) {
+	quotas := map[string]storage.Quota{
+		auth.RoleUser: {MaxActive: 100, MaxPerDay: 10},
+	}
+
+	cases := []struct {
+		name      string
+		user      *auth.User
+		usage     storage.QuotaUsage
+		mockError error
+		status    int
+		respError string
+		active    quota.Usage
+		daily     quota.Usage
+	}{
+		{
+			name:   "Success",
+			user:   &auth.User{Name: "bob", Role: auth.RoleUser},
+			usage:  storage.QuotaUsage{Active: 5, Today: 2},
+			status: http.StatusOK,
+			active: quota.Usage{Used: 5, Limit: 100},
+			daily:  quota.Usage{Used: 2, Limit: 10},
+		},
+		{
+			name:   "Role without quota",
+			user:   &auth.User{Name: "admin", Role: auth.RoleAdmin},
+			usage:  storage.QuotaUsage{Active: 7, Today: 7},
+			status: http.StatusOK,
+			active: quota.Usage{Used: 7},
+			daily:  quota.Usage{Used: 7},
+		},
+		{
+			name:      "No user",
+			status:    http.StatusUnauthorized,
+			respError: "unauthorized",
+		},
+		{
+			name:      "QuotaUsage Error",
+			user:      &auth.User{Name: "bob", Role: auth.RoleUser},
+			mockError: errors.New("unexpected error"),
+			status:    http.StatusInternalServerError,
+			respError: "internal error",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			usageGetterMock := mocks.NewUsageGetter(t)
+
+			if tc.user != nil {
+				usageGetterMock.On("QuotaUsage", mock.Anything, tc.user.Name).
+					Return(tc.usage, tc.mockError).
+					Once()
+			}
+
+			handler := quota.New(slogdiscard.NewDiscardLogger(), usageGetterMock, quotas)
+
+			req := httptest.NewRequest(http.MethodGet, "/me/quota", nil)
+			if tc.user != nil {
+				req = req.WithContext(auth.WithUser(req.Context(), *tc.user))
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var resp quota.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError != "" {
+				return
+			}
+
+			require.Equal(t, tc.active, *resp.Active)
+			require.Equal(t, tc.daily, *resp.Daily)
+		})
+	}
+}
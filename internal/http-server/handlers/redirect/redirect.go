@@ -1,6 +1,7 @@
 package redirect
 
 import (
+	"context"
 	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -8,11 +9,12 @@ import (
 	"log/slog"
 	"net/http"
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/metrics"
 	"url-shortener/internal/storage"
 )
 
 type URLGetter interface {
-	GetURL(alias string) (string, error)
+	GetURL(ctx context.Context, alias string) (string, error)
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLGetter
@@ -34,18 +36,29 @@ func New(log *slog.Logger, urlSaver URLGetter) http.HandlerFunc {
 			return
 		}
 
-		resURL, err := urlSaver.GetURL(ailas)
+		resURL, err := urlSaver.GetURL(r.Context(), ailas)
 		if errors.Is(err, storage.ErrUrlNotFound) {
+			metrics.Redirects.WithLabelValues(metrics.RedirectMiss).Inc()
 			log.Info("url not found", "alias", ailas)
+			render.Status(r, http.StatusNotFound)
 			render.JSON(w, r, resp.Error("url not found"))
 			return
 		}
+		if errors.Is(err, storage.ErrUrlDeleted) {
+			metrics.Redirects.WithLabelValues(metrics.RedirectExpired).Inc()
+			log.Info("url deleted", "alias", ailas)
+			render.Status(r, http.StatusGone)
+			render.JSON(w, r, resp.Error("url deleted"))
+			return
+		}
 		if err != nil {
+			metrics.Redirects.WithLabelValues(metrics.RedirectError).Inc()
 			log.Info("failed to get url", "alias", ailas)
 			render.JSON(w, r, resp.Error("internal error"))
 			return
 		}
 
+		metrics.Redirects.WithLabelValues(metrics.RedirectHit).Inc()
 		log.Info("got url", slog.String("url", resURL))
 
 		http.Redirect(w, r, resURL, http.StatusFound)
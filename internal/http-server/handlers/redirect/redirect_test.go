@@ -1,17 +1,22 @@
 package redirect_test
 
 import (
+	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 	"url-shortener/internal/http-server/handlers/redirect"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"url-shortener/internal/http-server/handlers/redirect/mocks"
 	"url-shortener/internal/lib/api"
+	"url-shortener/internal/lib/api/response"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
 )
 
 func TestSaveHandler(t *testing.T) {
@@ -20,6 +25,7 @@ func TestSaveHandler(t *testing.T) {
 		alias     string
 		url       string
 		respError string
+		status    int
 		mockError error
 	}{
 		{
@@ -27,6 +33,20 @@ func TestSaveHandler(t *testing.T) {
 			alias: "test_alias",
 			url:   "https://www.google.com/",
 		},
+		{
+			name:      "Not found",
+			alias:     "missing_alias",
+			respError: "url not found",
+			status:    http.StatusNotFound,
+			mockError: storage.ErrUrlNotFound,
+		},
+		{
+			name:      "Deleted",
+			alias:     "deleted_alias",
+			respError: "url deleted",
+			status:    http.StatusGone,
+			mockError: storage.ErrUrlDeleted,
+		},
 	}
 
 	for _, tc := range cases {
@@ -34,13 +54,26 @@ func TestSaveHandler(t *testing.T) {
 			urlGetterMock := mocks.NewURLGetter(t)
 
 			if tc.respError == "" || tc.mockError != nil {
-				urlGetterMock.On("GetURL", tc.alias).
+				urlGetterMock.On("GetURL", mock.Anything, tc.alias).
 					Return(tc.url, tc.mockError).Once()
 			}
 
 			r := chi.NewRouter()
 			r.Get("/{alias}", redirect.New(slogdiscard.NewDiscardLogger(), urlGetterMock))
 
+			if tc.mockError != nil {
+				rr := httptest.NewRecorder()
+				r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/"+tc.alias, nil))
+
+				require.Equal(t, tc.status, rr.Code)
+
+				var resp response.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				assert.Equal(t, tc.respError, resp.Error)
+
+				return
+			}
+
 			ts := httptest.NewServer(r)
 			defer ts.Close()
 
@@ -0,0 +1,124 @@
+package restore
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
+)
+
+// UrlRestorer пишет entry в журнал в той же транзакции, что и восстановление
+type UrlRestorer interface {
+	RestoreUrl(ctx context.Context, alias string, owner string, quota storage.Quota, entry storage.AuditEntry) (string, error)
+}
+
+type OwnerFinder interface {
+	UrlOwner(ctx context.Context, alias string) (string, error)
+}
+
+// RoleResolver - роль владельца ссылки: квота при восстановлении - его, а не того, кто восстанавливает
+type RoleResolver interface {
+	Role(name string) string
+}
+
+type Response struct {
+	resp.Response
+	Alias string `json:"alias,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+const CodeActiveQuotaExceeded = "active_quota_exceeded"
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UrlRestorer
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=OwnerFinder
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=RoleResolver
+func New(
+	log *slog.Logger,
+	urlRestorer UrlRestorer,
+	ownerFinder OwnerFinder,
+	quotas map[string]storage.Quota,
+	roles RoleResolver,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.restore.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.JSON(w, r, resp.Error("invalid request"))
+
+			return
+		}
+
+		owner, err := ownerFinder.UrlOwner(r.Context(), alias)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("deleted url not found", "alias", alias)
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error("deleted url not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url owner", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+
+			return
+		}
+
+		user, _ := auth.UserFromContext(r.Context())
+		if !auth.CanManage(user, owner) {
+			log.Info("user is not the owner", slog.String("user", user.Name), slog.String("alias", alias))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error("forbidden"))
+
+			return
+		}
+
+		entry := audit.NewEntry(r, audit.ActionRestore, alias, "", "")
+		// клиент может отключиться, но начатое восстановление доводим до конца вместе с записью в журнал
+		ctx := context.WithoutCancel(r.Context())
+		restoredURL, err := urlRestorer.RestoreUrl(ctx, alias, owner, quotas[roles.Role(owner)], entry)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("deleted url not found", "alias", alias)
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error("deleted url not found"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrActiveQuotaExceeded) {
+			log.Info("active links quota exceeded", slog.String("owner", owner))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.ErrorCode(CodeActiveQuotaExceeded, "active links quota exceeded"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to restore url", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+
+			return
+		}
+
+		log.Info("url restored", slog.String("alias", alias))
+
+		render.JSON(w, r, Response{
+			Response: resp.Ok(),
+			Alias:    alias,
+			URL:      restoredURL,
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package restore_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/restore"
+	"url-shortener/internal/http-server/handlers/url/restore/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
+)
+
+func TestRestoreHandler(t *testing.T) {
+	quotas := map[string]storage.Quota{
+		auth.RoleUser:  {MaxActive: 10},
+		auth.RoleAdmin: {},
+	}
+
+	bob := auth.User{Name: "bob", Role: auth.RoleUser}
+
+	cases := []struct {
+		name       string
+		alias      string
+		user       auth.User
+		owner      string
+		ownerError error
+		restored   string
+		respError  string
+		respCode   string
+		status     int
+		mockError  error
+	}{
+		{
+			name:     "Success",
+			alias:    "test_alias",
+			user:     bob,
+			owner:    "bob",
+			restored: "https://google.com",
+		},
+		{
+			name:      "Empty alias",
+			alias:     "",
+			user:      bob,
+			respError: "invalid request",
+		},
+		{
+			name:       "Not found",
+			alias:      "test_alias",
+			user:       bob,
+			ownerError: storage.ErrUrlNotFound,
+			respError:  "deleted url not found",
+			status:     http.StatusNotFound,
+		},
+		{
+			name:      "Not deleted",
+			alias:     "test_alias",
+			user:      bob,
+			owner:     "bob",
+			respError: "deleted url not found",
+			status:    http.StatusNotFound,
+			mockError: storage.ErrUrlNotFound,
+		},
+		{
+			name:      "Not owner",
+			alias:     "test_alias",
+			user:      auth.User{Name: "alice", Role: auth.RoleUser},
+			owner:     "bob",
+			respError: "forbidden",
+			status:    http.StatusForbidden,
+		},
+		{
+			// квота владельца, а не администратора
+			name:     "Admin restores any url",
+			alias:    "test_alias",
+			user:     auth.User{Name: "admin", Role: auth.RoleAdmin},
+			owner:    "bob",
+			restored: "https://google.com",
+		},
+		{
+			name:      "Quota exceeded",
+			alias:     "test_alias",
+			user:      bob,
+			owner:     "bob",
+			respError: "active links quota exceeded",
+			respCode:  restore.CodeActiveQuotaExceeded,
+			status:    http.StatusForbidden,
+			mockError: storage.ErrActiveQuotaExceeded,
+		},
+		{
+			name:      "RestoreUrl Error",
+			alias:     "test_alias",
+			user:      bob,
+			owner:     "bob",
+			respError: "internal error",
+			mockError: errors.New("unexpected error"),
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlRestorerMock := mocks.NewUrlRestorer(t)
+			ownerFinderMock := mocks.NewOwnerFinder(t)
+			roleResolverMock := mocks.NewRoleResolver(t)
+
+			if tc.alias != "" {
+				ownerFinderMock.On("UrlOwner", mock.Anything, tc.alias).
+					Return(tc.owner, tc.ownerError).
+					Once()
+			}
+
+			if tc.owner != "" && auth.CanManage(tc.user, tc.owner) {
+				roleResolverMock.On("Role", tc.owner).
+					Return(auth.RoleUser).
+					Once()
+
+				urlRestorerMock.On("RestoreUrl", mock.Anything, tc.alias, tc.owner, quotas[auth.RoleUser],
+					mock.MatchedBy(func(e storage.AuditEntry) bool {
+						return e.Action == audit.ActionRestore && e.Alias == tc.alias && e.Actor == tc.user.Name
+					})).
+					Return(tc.restored, tc.mockError).
+					Once()
+			}
+
+			handler := restore.New(slogdiscard.NewDiscardLogger(), urlRestorerMock, ownerFinderMock, quotas, roleResolverMock)
+
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/url/%s/restore", tc.alias), nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("alias", tc.alias)
+
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			ctx = auth.WithUser(ctx, tc.user)
+			req = req.WithContext(ctx)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			status := tc.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			require.Equal(t, status, rr.Code)
+
+			var resp restore.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+			require.Equal(t, tc.restored, resp.URL)
+		})
+	}
+}
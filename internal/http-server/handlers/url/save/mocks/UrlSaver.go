@@ -2,30 +2,35 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+	storage "url-shortener/internal/storage"
+)
 
 // UrlSaver is an autogenerated mock type for the UrlSaver type
 type UrlSaver struct {
 	mock.Mock
 }
 
-// SaveUrl provides a mock function with given fields: urlToSave, alias
-func (_m *UrlSaver) SaveUrl(urlToSave string, alias string) (int64, error) {
-	ret := _m.Called(urlToSave, alias)
+// SaveUrl provides a mock function with given fields: ctx, urlToSave, alias, owner, quota, entry
+func (_m *UrlSaver) SaveUrl(ctx context.Context, urlToSave string, alias string, owner string, quota storage.Quota, entry storage.AuditEntry) (int64, error) {
+	ret := _m.Called(ctx, urlToSave, alias, owner, quota, entry)
 
 	var r0 int64
 	var r1 error
-	if rf, ok := ret.Get(0).(func(string, string) (int64, error)); ok {
-		return rf(urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, storage.Quota, storage.AuditEntry) (int64, error)); ok {
+		return rf(ctx, urlToSave, alias, owner, quota, entry)
 	}
-	if rf, ok := ret.Get(0).(func(string, string) int64); ok {
-		r0 = rf(urlToSave, alias)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string, storage.Quota, storage.AuditEntry) int64); ok {
+		r0 = rf(ctx, urlToSave, alias, owner, quota, entry)
 	} else {
 		r0 = ret.Get(0).(int64)
 	}
 
-	if rf, ok := ret.Get(1).(func(string, string) error); ok {
-		r1 = rf(urlToSave, alias)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string, storage.Quota, storage.AuditEntry) error); ok {
+		r1 = rf(ctx, urlToSave, alias, owner, quota, entry)
 	} else {
 		r1 = ret.Error(1)
 	}
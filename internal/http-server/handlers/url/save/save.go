@@ -1,36 +1,95 @@
 package save
 
 import (
+	"context"
 	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"github.com/go-playground/validator/v10"
 	"log/slog"
 	"net/http"
+	"url-shortener/internal/http-server/middleware/auth"
+	aliaslib "url-shortener/internal/lib/alias"
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/audit"
 	"url-shortener/internal/lib/logger/sl"
 	"url-shortener/internal/lib/random"
+	"url-shortener/internal/lib/urlchain"
 	"url-shortener/internal/storage"
 )
 
 type Request struct {
 	URL   string `json:"url" validate:"required,url"`
-	Alias string `json:"alias,omitempty"`
+	Alias string `json:"alias,omitempty" validate:"omitempty,max=64,alias"`
+	// вернуть уже существующую ссылку пользователя на этот url вместо создания новой
+	ReuseExisting bool `json:"reuse_existing,omitempty"`
 }
 
 type Response struct {
 	resp.Response
-	Alias string `json:"alias,omitempty"`
+	Alias  string `json:"alias,omitempty"`
+	Reused bool   `json:"reused,omitempty"` // alias взят из существующей ссылки
 }
 
+// UrlSaver пишет entry в журнал в той же транзакции, что и создание ссылки
 type UrlSaver interface {
-	SaveUrl(urlToSave string, alias string) (int64, error)
+	SaveUrl(
+		ctx context.Context,
+		urlToSave string,
+		alias string,
+		owner string,
+		quota storage.Quota,
+		entry storage.AuditEntry,
+	) (int64, error)
 }
 
-const aliasLength = 6
+// URLChecker - политика допустимых url (схемы, домены)
+type URLChecker interface {
+	Check(ctx context.Context, rawURL string) error
+}
+
+// ChainResolver - ссылки на наши же короткие ссылки: проверка циклов, схлопывание цепочек
+type ChainResolver interface {
+	Resolve(ctx context.Context, rawURL string, alias string) (string, error)
+}
+
+// AliasChecker - зарезервированные и запрещенные alias
+type AliasChecker interface {
+	Check(alias string) error
+}
+
+// URLFinder - поиск ссылки владельца на тот же url, нужен для reuse_existing
+type URLFinder interface {
+	AliasByURL(ctx context.Context, urlToFind string, owner string) (string, error)
+}
+
+const (
+	aliasLength = 6
+	// сколько раз пробуем сгенерировать alias, который пройдет AliasChecker
+	aliasAttempts = 5
+)
+
+const (
+	CodeActiveQuotaExceeded = "active_quota_exceeded"
+	CodeDailyQuotaExceeded  = "daily_quota_exceeded"
+)
 
 //go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UrlSaver
-func New(log *slog.Logger, urlSaver UrlSaver) http.HandlerFunc {
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLChecker
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ChainResolver
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=AliasChecker
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLFinder
+func New(
+	log *slog.Logger,
+	urlSaver UrlSaver,
+	quotas map[string]storage.Quota,
+	urlChecker URLChecker,
+	chainResolver ChainResolver,
+	aliasChecker AliasChecker,
+	urlFinder URLFinder,
+) http.HandlerFunc {
+	validate := aliaslib.NewValidator()
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.save.New"
 
@@ -50,7 +109,7 @@ func New(log *slog.Logger, urlSaver UrlSaver) http.HandlerFunc {
 
 		log.Info("request body decoded", slog.Any("request", req))
 
-		if err := validator.New().Struct(req); err != nil {
+		if err := validate.Struct(req); err != nil {
 			validatorErr := err.(validator.ValidationErrors)
 
 			log.Error("invalid request", sl.Err(err))
@@ -61,12 +120,97 @@ func New(log *slog.Logger, urlSaver UrlSaver) http.HandlerFunc {
 		}
 
 		alias := req.Alias
-		if alias == "" {
+		if alias != "" {
+			if err := aliasChecker.Check(alias); err != nil {
+				log.Info("alias is not allowed", sl.Err(err))
+
+				render.JSON(w, r, resp.Error(err.Error()))
+
+				return
+			}
+		} else {
 			// TODO можем сгенерить существующий alias
-			alias = random.NewRandomString(aliasLength)
+			alias, err = randomAlias(aliasChecker)
+			if err != nil {
+				log.Error("failed to generate alias", sl.Err(err))
+
+				render.JSON(w, r, resp.Error("failed to add url"))
+
+				return
+			}
 		}
 
-		id, err := urlSaver.SaveUrl(req.URL, alias)
+		// alias нужен, чтобы поймать ссылку на саму себя
+		urlToSave, err := chainResolver.Resolve(r.Context(), req.URL, alias)
+		if errors.Is(err, urlchain.ErrShortLink) ||
+			errors.Is(err, urlchain.ErrRedirectLoop) ||
+			errors.Is(err, urlchain.ErrChainTooDeep) ||
+			errors.Is(err, urlchain.ErrBrokenChain) ||
+			errors.Is(err, urlchain.ErrServicePath) {
+			log.Info("invalid short link chain", sl.Err(err))
+
+			render.JSON(w, r, resp.Error(err.Error()))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to resolve short link chain", sl.Err(err))
+
+			render.JSON(w, r, resp.Error("failed to add url"))
+
+			return
+		}
+
+		if err := urlChecker.Check(r.Context(), urlToSave); err != nil {
+			log.Info("url is not allowed", sl.Err(err))
+
+			// как и квоты: запрос корректный, но политика его не пропускает
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(err.Error()))
+
+			return
+		}
+
+		user, _ := auth.UserFromContext(r.Context())
+
+		if req.ReuseExisting {
+			existing, err := urlFinder.AliasByURL(r.Context(), urlToSave, user.Name)
+			if err == nil {
+				log.Info("url already shortened", slog.String("alias", existing))
+				render.JSON(w, r, Response{
+					Response: resp.Ok(),
+					Alias:    existing,
+					Reused:   true,
+				})
+
+				return
+			}
+			if !errors.Is(err, storage.ErrUrlNotFound) {
+				log.Error("failed to find existing url", sl.Err(err))
+				render.JSON(w, r, resp.Error("failed to add url"))
+
+				return
+			}
+		}
+
+		// quotas - ограничения по ролям, для роли без квоты ограничений нет
+		entry := audit.NewEntry(r, audit.ActionCreate, alias, "", urlToSave)
+		// все проверки пройдены: сохраняем, даже если клиент уже отключился
+		id, err := urlSaver.SaveUrl(context.WithoutCancel(r.Context()), urlToSave, alias, user.Name, quotas[user.Role], entry)
+		if errors.Is(err, storage.ErrActiveQuotaExceeded) {
+			log.Info("active links quota exceeded", slog.String("user", user.Name))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.ErrorCode(CodeActiveQuotaExceeded, "active links quota exceeded"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrDailyQuotaExceeded) {
+			log.Info("daily links quota exceeded", slog.String("user", user.Name))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.ErrorCode(CodeDailyQuotaExceeded, "daily links quota exceeded"))
+
+			return
+		}
 		if errors.Is(err, storage.ErrUrlExists) {
 			log.Info("url already exist", slog.String("url", req.URL))
 			render.JSON(w, r, resp.Error("url already exist"))
@@ -86,6 +230,18 @@ func New(log *slog.Logger, urlSaver UrlSaver) http.HandlerFunc {
 	}
 }
 
+func randomAlias(aliasChecker AliasChecker) (string, error) {
+	var err error
+	for i := 0; i < aliasAttempts; i++ {
+		alias := random.NewRandomString(aliasLength)
+		if err = aliasChecker.Check(alias); err == nil {
+			return alias, nil
+		}
+	}
+
+	return "", err
+}
+
 func responseOk(w http.ResponseWriter, r *http.Request, alias string) {
 	render.JSON(w, r, Response{
 		Response: resp.Ok(),
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
@@ -14,16 +15,30 @@ import (
 
 	"url-shortener/internal/http-server/handlers/url/save"
 	"url-shortener/internal/http-server/handlers/url/save/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	aliaslib "url-shortener/internal/lib/alias"
+	"url-shortener/internal/lib/audit"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/urlchain"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage"
 )
 
 func TestSaveHandler(t *testing.T) {
 	cases := []struct {
-		name      string
-		alias     string
-		url       string
-		respError string
-		mockError error
+		name       string
+		alias      string
+		url        string
+		respError  string
+		respCode   string
+		status     int
+		mockError  error
+		checkError error
+		chainError error
+		aliasError error
+		resolved   string // url после схлопывания цепочки коротких ссылок
+		reuse      bool
+		existing   string // alias уже существующей ссылки на url
 	}{
 		{
 			name:  "Success",
@@ -47,6 +62,25 @@ func TestSaveHandler(t *testing.T) {
 			alias:     "some_alias",
 			respError: "field URL is not a valid URL",
 		},
+		{
+			name:      "Invalid alias",
+			url:       "https://google.com",
+			alias:     "bad alias!",
+			respError: "field Alias may contain only latin letters, digits, '-' and '_'",
+		},
+		{
+			name:      "Too long alias",
+			url:       "https://google.com",
+			alias:     strings.Repeat("a", 65),
+			respError: "field Alias must be at most 64 characters long",
+		},
+		{
+			name:       "Reserved alias",
+			url:        "https://google.com",
+			alias:      "metrics",
+			respError:  "alias is reserved: metrics",
+			aliasError: fmt.Errorf("%w: metrics", aliaslib.ErrReserved),
+		},
 		{
 			name:      "SaveURL Error",
 			alias:     "test_alias",
@@ -54,6 +88,69 @@ func TestSaveHandler(t *testing.T) {
 			respError: "failed to add url",
 			mockError: errors.New("unexpected error"),
 		},
+		{
+			name:      "Active quota exceeded",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			respError: "active links quota exceeded",
+			respCode:  save.CodeActiveQuotaExceeded,
+			status:    http.StatusForbidden,
+			mockError: storage.ErrActiveQuotaExceeded,
+		},
+		{
+			name:      "Daily quota exceeded",
+			alias:     "test_alias",
+			url:       "https://google.com",
+			respError: "daily links quota exceeded",
+			respCode:  save.CodeDailyQuotaExceeded,
+			status:    http.StatusForbidden,
+			mockError: storage.ErrDailyQuotaExceeded,
+		},
+		{
+			name:       "URL not allowed",
+			alias:      "test_alias",
+			url:        "https://evil.com",
+			respError:  "url domain is blocked: evil.com",
+			status:     http.StatusForbidden,
+			checkError: fmt.Errorf("%w: evil.com", urlpolicy.ErrDomainBlocked),
+		},
+		{
+			name:     "Flattened short link",
+			alias:    "test_alias",
+			url:      "https://short.io/google",
+			resolved: "https://google.com",
+		},
+		{
+			name:       "Redirect loop",
+			alias:      "test_alias",
+			url:        "https://short.io/test_alias",
+			respError:  "url creates a redirect loop",
+			chainError: urlchain.ErrRedirectLoop,
+		},
+		{
+			name:     "Reuse existing",
+			alias:    "test_alias",
+			url:      "https://google.com",
+			reuse:    true,
+			existing: "found1",
+		},
+		{
+			name:  "Reuse not found",
+			alias: "test_alias",
+			url:   "https://google.com",
+			reuse: true,
+		},
+		{
+			name:       "Resolve chain Error",
+			alias:      "test_alias",
+			url:        "https://short.io/google",
+			respError:  "failed to add url",
+			chainError: errors.New("unexpected error"),
+		},
+	}
+
+	quotas := map[string]storage.Quota{
+		auth.RoleUser: {MaxActive: 10, MaxPerDay: 5},
 	}
 
 	for _, tc := range cases {
@@ -63,24 +160,80 @@ func TestSaveHandler(t *testing.T) {
 			t.Parallel()
 
 			urlSaverMock := mocks.NewUrlSaver(t)
+			urlCheckerMock := mocks.NewURLChecker(t)
+			chainResolverMock := mocks.NewChainResolver(t)
+			aliasCheckerMock := mocks.NewAliasChecker(t)
+			urlFinderMock := mocks.NewURLFinder(t)
+
+			resolved := tc.resolved
+			if resolved == "" {
+				resolved = tc.url
+			}
+
+			// запрос прошел валидацию
+			if tc.respError == "" || tc.mockError != nil || tc.checkError != nil || tc.chainError != nil || tc.aliasError != nil {
+				aliasCheckerMock.On("Check", mock.AnythingOfType("string")).
+					Return(tc.aliasError).
+					Once()
+			}
 
-			if tc.respError == "" || tc.mockError != nil {
-				urlSaverMock.On("SaveUrl", tc.url, mock.AnythingOfType("string")).
+			if tc.respError == "" || tc.mockError != nil || tc.checkError != nil || tc.chainError != nil {
+				chainResolverMock.On("Resolve", mock.Anything, tc.url, mock.AnythingOfType("string")).
+					Return(resolved, tc.chainError).
+					Once()
+			}
+
+			if (tc.respError == "" || tc.mockError != nil || tc.checkError != nil) && tc.chainError == nil {
+				urlCheckerMock.On("Check", mock.Anything, resolved).
+					Return(tc.checkError).
+					Once()
+			}
+
+			if tc.reuse {
+				findError := error(nil)
+				if tc.existing == "" {
+					findError = storage.ErrUrlNotFound
+				}
+
+				urlFinderMock.On("AliasByURL", mock.Anything, resolved, "bob").
+					Return(tc.existing, findError).
+					Once()
+			}
+
+			if (tc.respError == "" || tc.mockError != nil) && tc.checkError == nil && tc.chainError == nil && tc.existing == "" {
+				urlSaverMock.On("SaveUrl", mock.Anything, resolved, mock.AnythingOfType("string"), "bob", quotas[auth.RoleUser],
+					mock.MatchedBy(func(e storage.AuditEntry) bool {
+						return e.Action == audit.ActionCreate && e.Actor == "bob" && e.NewValue == resolved && e.OldValue == ""
+					})).
 					Return(int64(1), tc.mockError).
 					Once()
 			}
 
-			handler := save.New(slogdiscard.NewDiscardLogger(), urlSaverMock)
+			handler := save.New(
+				slogdiscard.NewDiscardLogger(),
+				urlSaverMock,
+				quotas,
+				urlCheckerMock,
+				chainResolverMock,
+				aliasCheckerMock,
+				urlFinderMock,
+			)
 
-			input := fmt.Sprintf(`{"url": "%s", "alias": "%s"}`, tc.url, tc.alias)
+			input := fmt.Sprintf(`{"url": "%s", "alias": "%s", "reuse_existing": %t}`, tc.url, tc.alias, tc.reuse)
 
 			req, err := http.NewRequest(http.MethodPost, "/save", bytes.NewReader([]byte(input)))
 			require.NoError(t, err)
 
+			req = req.WithContext(auth.WithUser(req.Context(), auth.User{Name: "bob", Role: auth.RoleUser}))
+
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 
-			require.Equal(t, rr.Code, http.StatusOK)
+			status := tc.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			require.Equal(t, rr.Code, status)
 
 			body := rr.Body.String()
 
@@ -89,6 +242,12 @@ func TestSaveHandler(t *testing.T) {
 			require.NoError(t, json.Unmarshal([]byte(body), &resp))
 
 			require.Equal(t, tc.respError, resp.Error)
+			require.Equal(t, tc.respCode, resp.Code)
+			require.Equal(t, tc.existing != "", resp.Reused)
+
+			if tc.existing != "" {
+				require.Equal(t, tc.existing, resp.Alias)
+			}
 
 			// TODO: add more checks
 		})
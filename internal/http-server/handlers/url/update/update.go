@@ -0,0 +1,177 @@
+package update
+
+import (
+	"context"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"log/slog"
+	"net/http"
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/lib/urlchain"
+	"url-shortener/internal/storage"
+)
+
+type Request struct {
+	URL string `json:"url" validate:"required,url"`
+}
+
+type Response struct {
+	resp.Response
+	Alias string `json:"alias,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// UrlUpdater пишет entry в журнал в той же транзакции, что и изменение
+type UrlUpdater interface {
+	UpdateUrl(ctx context.Context, alias string, owner string, urlToSave string, entry storage.AuditEntry) (string, error)
+}
+
+type OwnerFinder interface {
+	UrlOwner(ctx context.Context, alias string) (string, error)
+}
+
+// URLChecker - политика допустимых url, как при создании
+type URLChecker interface {
+	Check(ctx context.Context, rawURL string) error
+}
+
+// ChainResolver - ссылки на наши же короткие ссылки и пути сервиса, как при создании
+type ChainResolver interface {
+	Resolve(ctx context.Context, rawURL string, alias string) (string, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UrlUpdater
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=OwnerFinder
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=URLChecker
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=ChainResolver
+func New(
+	log *slog.Logger,
+	urlUpdater UrlUpdater,
+	ownerFinder OwnerFinder,
+	urlChecker URLChecker,
+	chainResolver ChainResolver,
+) http.HandlerFunc {
+	validate := validator.New()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.url.update.New"
+
+		log := log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+		)
+
+		alias := chi.URLParam(r, "alias")
+		if alias == "" {
+			log.Info("alias is empty")
+
+			render.JSON(w, r, resp.Error("invalid request"))
+
+			return
+		}
+
+		var req Request
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Error("failed to decode request body", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to decode request"))
+
+			return
+		}
+
+		if err := validate.Struct(req); err != nil {
+			validatorErr := err.(validator.ValidationErrors)
+
+			log.Error("invalid request", sl.Err(err))
+
+			render.JSON(w, r, resp.ValidationError(validatorErr))
+
+			return
+		}
+
+		owner, err := ownerFinder.UrlOwner(r.Context(), alias)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found", "alias", alias)
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error("url not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url owner", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+
+			return
+		}
+
+		user, _ := auth.UserFromContext(r.Context())
+		if !auth.CanManage(user, owner) {
+			log.Info("user is not the owner", slog.String("user", user.Name), slog.String("alias", alias))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error("forbidden"))
+
+			return
+		}
+
+		// те же проверки, что и при создании: новый url не должен вести на сервис, в цикл или во внутреннюю сеть
+		urlToSave, err := chainResolver.Resolve(r.Context(), req.URL, alias)
+		if errors.Is(err, urlchain.ErrShortLink) ||
+			errors.Is(err, urlchain.ErrRedirectLoop) ||
+			errors.Is(err, urlchain.ErrChainTooDeep) ||
+			errors.Is(err, urlchain.ErrBrokenChain) ||
+			errors.Is(err, urlchain.ErrServicePath) {
+			log.Info("invalid short link chain", sl.Err(err))
+
+			render.JSON(w, r, resp.Error(err.Error()))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to resolve short link chain", sl.Err(err))
+
+			render.JSON(w, r, resp.Error("failed to update url"))
+
+			return
+		}
+
+		if err := urlChecker.Check(r.Context(), urlToSave); err != nil {
+			log.Info("url is not allowed", sl.Err(err))
+
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error(err.Error()))
+
+			return
+		}
+
+		entry := audit.NewEntry(r, audit.ActionUpdate, alias, "", urlToSave)
+		// не отменяем вместе с запросом: изменение и запись в журнал должны пройти целиком
+		_, err = urlUpdater.UpdateUrl(context.WithoutCancel(r.Context()), alias, owner, urlToSave, entry)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			// удалили, пока проверяли
+			log.Info("url not found", "alias", alias)
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error("url not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to update url", sl.Err(err))
+			render.JSON(w, r, resp.Error("failed to update url"))
+
+			return
+		}
+
+		log.Info("url updated", slog.String("alias", alias))
+
+		render.JSON(w, r, Response{
+			Response: resp.Ok(),
+			Alias:    alias,
+			URL:      urlToSave,
+		})
+	}
+}
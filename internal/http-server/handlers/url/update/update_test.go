@@ -0,0 +1,203 @@
+package update_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/handlers/url/update"
+	"url-shortener/internal/http-server/handlers/url/update/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/urlchain"
+	"url-shortener/internal/lib/urlpolicy"
+	"url-shortener/internal/storage"
+)
+
+func TestUpdateHandler(t *testing.T) {
+	bob := auth.User{Name: "bob", Role: auth.RoleUser}
+
+	cases := []struct {
+		name       string
+		alias      string
+		url        string
+		user       auth.User
+		owner      string
+		ownerError error
+		chainError error
+		checkError error
+		resolved   string // url после схлопывания цепочки коротких ссылок
+		mockError  error
+		respError  string
+		status     int
+	}{
+		{
+			name:  "Success",
+			alias: "test_alias",
+			url:   "https://ya.ru",
+			user:  bob,
+			owner: "bob",
+		},
+		{
+			name:     "Flattened short link",
+			alias:    "test_alias",
+			url:      "https://short.io/google",
+			user:     bob,
+			owner:    "bob",
+			resolved: "https://google.com",
+		},
+		{
+			name:  "Admin updates any url",
+			alias: "test_alias",
+			url:   "https://ya.ru",
+			user:  auth.User{Name: "admin", Role: auth.RoleAdmin},
+			owner: "bob",
+		},
+		{
+			name:      "Invalid URL",
+			alias:     "test_alias",
+			url:       "some invalid URL",
+			user:      bob,
+			respError: "field URL is not a valid URL",
+		},
+		{
+			name:       "Not found",
+			alias:      "missing_alias",
+			url:        "https://ya.ru",
+			user:       bob,
+			ownerError: storage.ErrUrlNotFound,
+			respError:  "url not found",
+			status:     http.StatusNotFound,
+		},
+		{
+			name:      "Not owner",
+			alias:     "test_alias",
+			url:       "https://ya.ru",
+			user:      auth.User{Name: "alice", Role: auth.RoleUser},
+			owner:     "bob",
+			respError: "forbidden",
+			status:    http.StatusForbidden,
+		},
+		{
+			name:       "Service path",
+			alias:      "test_alias",
+			url:        "https://short.io/url/abc/restore",
+			user:       bob,
+			owner:      "bob",
+			chainError: urlchain.ErrServicePath,
+			respError:  urlchain.ErrServicePath.Error(),
+		},
+		{
+			name:       "Redirect loop",
+			alias:      "test_alias",
+			url:        "https://short.io/test_alias",
+			user:       bob,
+			owner:      "bob",
+			chainError: urlchain.ErrRedirectLoop,
+			respError:  urlchain.ErrRedirectLoop.Error(),
+		},
+		{
+			name:       "URL not allowed",
+			alias:      "test_alias",
+			url:        "https://evil.com",
+			user:       bob,
+			owner:      "bob",
+			checkError: fmt.Errorf("%w: evil.com", urlpolicy.ErrDomainBlocked),
+			respError:  "url domain is blocked: evil.com",
+			status:     http.StatusForbidden,
+		},
+		{
+			name:      "UpdateUrl Error",
+			alias:     "test_alias",
+			url:       "https://ya.ru",
+			user:      bob,
+			owner:     "bob",
+			mockError: errors.New("unexpected error"),
+			respError: "failed to update url",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			urlUpdaterMock := mocks.NewUrlUpdater(t)
+			ownerFinderMock := mocks.NewOwnerFinder(t)
+			urlCheckerMock := mocks.NewURLChecker(t)
+			chainResolverMock := mocks.NewChainResolver(t)
+
+			resolved := tc.resolved
+			if resolved == "" {
+				resolved = tc.url
+			}
+
+			if tc.owner != "" || tc.ownerError != nil {
+				ownerFinderMock.On("UrlOwner", mock.Anything, tc.alias).
+					Return(tc.owner, tc.ownerError).
+					Once()
+			}
+
+			allowed := tc.owner != "" && auth.CanManage(tc.user, tc.owner)
+			if allowed {
+				chainResolverMock.On("Resolve", mock.Anything, tc.url, tc.alias).
+					Return(resolved, tc.chainError).
+					Once()
+			}
+
+			if allowed && tc.chainError == nil {
+				urlCheckerMock.On("Check", mock.Anything, resolved).
+					Return(tc.checkError).
+					Once()
+			}
+
+			if allowed && tc.chainError == nil && tc.checkError == nil {
+				urlUpdaterMock.On("UpdateUrl", mock.Anything, tc.alias, tc.owner, resolved,
+					mock.MatchedBy(func(e storage.AuditEntry) bool {
+						return e.Action == audit.ActionUpdate && e.Alias == tc.alias && e.Actor == tc.user.Name
+					})).
+					Return("https://google.com", tc.mockError).
+					Once()
+			}
+
+			handler := update.New(slogdiscard.NewDiscardLogger(), urlUpdaterMock, ownerFinderMock, urlCheckerMock, chainResolverMock)
+
+			input := fmt.Sprintf(`{"url": "%s"}`, tc.url)
+			req := httptest.NewRequest(http.MethodPut, "/url/"+tc.alias, bytes.NewReader([]byte(input)))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("alias", tc.alias)
+
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(auth.WithUser(ctx, tc.user))
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			status := tc.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			require.Equal(t, status, rr.Code)
+
+			var resp update.Response
+
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			require.Equal(t, tc.respError, resp.Error)
+			if tc.respError == "" {
+				require.Equal(t, resolved, resp.URL)
+			}
+		})
+	}
+}
@@ -2,25 +2,40 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+	storage "url-shortener/internal/storage"
+)
 
 // UrlDeleter is an autogenerated mock type for the UrlDeleter type
 type UrlDeleter struct {
 	mock.Mock
 }
 
-// DeleteUrl provides a mock function with given fields: alias
-func (_m *UrlDeleter) DeleteUrl(alias string) error {
-	ret := _m.Called(alias)
+// DeleteUrl provides a mock function with given fields: ctx, alias, owner, entry
+func (_m *UrlDeleter) DeleteUrl(ctx context.Context, alias string, owner string, entry storage.AuditEntry) (string, error) {
+	ret := _m.Called(ctx, alias, owner, entry)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, storage.AuditEntry) (string, error)); ok {
+		return rf(ctx, alias, owner, entry)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, storage.AuditEntry) string); ok {
+		r0 = rf(ctx, alias, owner, entry)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(alias)
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, storage.AuditEntry) error); ok {
+		r1 = rf(ctx, alias, owner, entry)
 	} else {
-		r0 = ret.Error(0)
+		r1 = ret.Error(1)
 	}
 
-	return r0
+	return r0, r1
 }
 
 type mockConstructorTestingTNewUrlDeleter interface {
@@ -1,16 +1,27 @@
 package urldelete
 
 import (
+	"context"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
+	"url-shortener/internal/http-server/middleware/auth"
 	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/audit"
+	"url-shortener/internal/lib/logger/sl"
+	"url-shortener/internal/storage"
 )
 
+// UrlDeleter пишет entry в журнал в той же транзакции, что и удаление
 type UrlDeleter interface {
-	DeleteUrl(alias string) error
+	DeleteUrl(ctx context.Context, alias string, owner string, entry storage.AuditEntry) (string, error)
+}
+
+type OwnerFinder interface {
+	UrlOwner(ctx context.Context, alias string) (string, error)
 }
 
 type Response struct {
@@ -18,7 +29,8 @@ type Response struct {
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=UrlDeleter
-func New(log *slog.Logger, urlSaver UrlDeleter) http.HandlerFunc {
+//go:generate go run github.com/vektra/mockery/v2@v2.28.2 --name=OwnerFinder
+func New(log *slog.Logger, urlSaver UrlDeleter, ownerFinder OwnerFinder) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.url.urldelete.New"
 
@@ -36,7 +48,36 @@ func New(log *slog.Logger, urlSaver UrlDeleter) http.HandlerFunc {
 			return
 		}
 
-		err := urlSaver.DeleteUrl(alias)
+		owner, err := ownerFinder.UrlOwner(r.Context(), alias)
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found", "alias", alias)
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error("url not found"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to get url owner", sl.Err(err))
+			render.JSON(w, r, resp.Error("internal error"))
+			return
+		}
+
+		user, _ := auth.UserFromContext(r.Context())
+		if !auth.CanManage(user, owner) {
+			log.Info("user is not the owner", slog.String("user", user.Name), slog.String("alias", alias))
+			render.Status(r, http.StatusForbidden)
+			render.JSON(w, r, resp.Error("forbidden"))
+			return
+		}
+
+		// отключение клиента не должно прерывать удаление на середине
+		ctx := context.WithoutCancel(r.Context())
+		_, err = urlSaver.DeleteUrl(ctx, alias, owner, audit.NewEntry(r, audit.ActionDelete, alias, "", ""))
+		if errors.Is(err, storage.ErrUrlNotFound) {
+			log.Info("url not found", "alias", alias)
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, resp.Error("url not found"))
+			return
+		}
 		if err != nil {
 			log.Info("failed to delete url", "alias", alias)
 			render.JSON(w, r, resp.Error("internal error"))
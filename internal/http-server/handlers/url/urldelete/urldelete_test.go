@@ -6,34 +6,99 @@ import (
 	"errors"
 	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"url-shortener/internal/http-server/handlers/url/urldelete"
 	"url-shortener/internal/http-server/handlers/url/urldelete/mocks"
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/audit"
 	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/storage"
 )
 
 func TestDeleteHandler(t *testing.T) {
+	bob := auth.User{Name: "bob", Role: auth.RoleUser}
+
 	cases := []struct {
-		name      string
-		alias     string
-		respError string
-		mockError error
+		name       string
+		alias      string
+		user       auth.User
+		owner      string
+		ownerError error
+		deleted    string
+		respError  string
+		status     int
+		mockError  error
+		cancelled  bool // клиент отключился до удаления
 	}{
 		{
-			name:  "Success",
-			alias: "test_alias",
+			name:    "Success",
+			alias:   "test_alias",
+			user:    bob,
+			owner:   "bob",
+			deleted: "https://google.com",
+		},
+		{
+			name:       "Not found",
+			alias:      "missing_alias",
+			user:       bob,
+			ownerError: storage.ErrUrlNotFound,
+			respError:  "url not found",
+			status:     http.StatusNotFound,
+		},
+		{
+			name:      "Already deleted",
+			alias:     "test_alias",
+			user:      bob,
+			owner:     "bob",
+			respError: "url not found",
+			status:    http.StatusNotFound,
+			mockError: storage.ErrUrlNotFound,
+		},
+		{
+			name:      "Not owner",
+			alias:     "test_alias",
+			user:      auth.User{Name: "alice", Role: auth.RoleUser},
+			owner:     "bob",
+			respError: "forbidden",
+			status:    http.StatusForbidden,
+		},
+		{
+			name:    "Admin deletes any url",
+			alias:   "test_alias",
+			user:    auth.User{Name: "admin", Role: auth.RoleAdmin},
+			owner:   "bob",
+			deleted: "https://google.com",
+		},
+		{
+			name:      "Client gone",
+			alias:     "test_alias",
+			user:      bob,
+			owner:     "bob",
+			deleted:   "https://google.com",
+			cancelled: true,
 		},
 		{
 			name:      "Empty alias",
 			alias:     "",
+			user:      bob,
 			respError: "invalid request",
 		},
+		{
+			name:       "UrlOwner Error",
+			alias:      "test_alias",
+			user:       bob,
+			ownerError: errors.New("unexpected error"),
+			respError:  "internal error",
+		},
 		{
 			name:      "DeleteURL Error",
 			alias:     "test_alias",
+			user:      bob,
+			owner:     "bob",
 			respError: "internal error",
 			mockError: errors.New("unexpected error"),
 		},
@@ -46,28 +111,47 @@ func TestDeleteHandler(t *testing.T) {
 			t.Parallel()
 
 			urlDeleterMock := mocks.NewUrlDeleter(t)
+			ownerFinderMock := mocks.NewOwnerFinder(t)
 
-			if tc.respError == "" || tc.mockError != nil {
-				urlDeleterMock.On("DeleteUrl", tc.alias).
-					Return(tc.mockError).
+			if tc.alias != "" {
+				ownerFinderMock.On("UrlOwner", mock.Anything, tc.alias).
+					Return(tc.owner, tc.ownerError).
 					Once()
 			}
 
-			handler := urldelete.New(slogdiscard.NewDiscardLogger(), urlDeleterMock)
+			if tc.owner != "" && auth.CanManage(tc.user, tc.owner) {
+				notCancelled := mock.MatchedBy(func(ctx context.Context) bool { return ctx.Err() == nil })
+				urlDeleterMock.On("DeleteUrl", notCancelled, tc.alias, tc.owner, mock.MatchedBy(func(e storage.AuditEntry) bool {
+					return e.Action == audit.ActionDelete && e.Alias == tc.alias && e.Actor == tc.user.Name
+				})).
+					Return(tc.deleted, tc.mockError).
+					Once()
+			}
+
+			handler := urldelete.New(slogdiscard.NewDiscardLogger(), urlDeleterMock, ownerFinderMock)
 
 			uri := fmt.Sprintf("/url/{%s}", tc.alias)
 			req, err := http.NewRequest(http.MethodDelete, uri, nil)
 			rctx := chi.NewRouteContext()
 			rctx.URLParams.Add("alias", tc.alias)
 
-			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			ctx, cancel := context.WithCancel(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			defer cancel()
+			if tc.cancelled {
+				cancel()
+			}
+			req = req.WithContext(auth.WithUser(ctx, tc.user))
 
 			require.NoError(t, err)
 
 			rr := httptest.NewRecorder()
 			handler.ServeHTTP(rr, req)
 
-			require.Equal(t, rr.Code, http.StatusOK)
+			status := tc.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			require.Equal(t, rr.Code, status)
 
 			body := rr.Body.String()
 
@@ -75,6 +159,8 @@ func TestDeleteHandler(t *testing.T) {
 
 			require.NoError(t, json.Unmarshal([]byte(body), &resp))
 
+			require.Equal(t, tc.respError, resp.Error)
+
 			// TODO: add more checks
 		})
 	}
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/go-chi/render"
+
+	"url-shortener/internal/clients/sso"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/sl"
+)
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// User - пользователь, прошедший BasicAuth
+type User struct {
+	Name string
+	Role string
+	UID  int64 // id пользователя в SSO, 0 - не задан
+}
+
+// Credentials - пароль, роль и id в SSO пользователя из конфига
+type Credentials struct {
+	Password string
+	Role     string
+	UID      int64
+}
+
+// Users - пользователи BasicAuth. Список можно заменить на лету, например при перечитывании конфига
+type Users struct {
+	m atomic.Pointer[map[string]Credentials]
+}
+
+func NewUsers(users map[string]Credentials) *Users {
+	u := &Users{}
+	u.Set(users)
+
+	return u
+}
+
+// Set заменяет список целиком: запросы видят либо старый, либо новый список
+func (u *Users) Set(users map[string]Credentials) {
+	u.m.Store(&users)
+}
+
+// Role - роль пользователя, для неизвестного и пользователя без роли - RoleUser
+func (u *Users) Role(name string) string {
+	if creds, ok := u.get(name); ok && creds.Role != "" {
+		return creds.Role
+	}
+
+	return RoleUser
+}
+
+func (u *Users) get(name string) (Credentials, bool) {
+	creds, ok := (*u.m.Load())[name]
+
+	return creds, ok
+}
+
+// AdminChecker - SSO
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID int64) (bool, error)
+}
+
+type (
+	ctxKey    struct{}
+	holderKey struct{}
+)
+
+// userHolder - куда WithUser записывает пользователя для middleware, которые стоят раньше auth
+type userHolder struct {
+	user User
+	ok   bool
+}
+
+// New - BasicAuth как в chi, но дополнительно кладет пользователя в контекст запроса
+func New(realm string, users *Users) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name, pass, ok := r.BasicAuth()
+			if !ok {
+				basicAuthFailed(w, realm)
+				return
+			}
+
+			creds, ok := users.get(name)
+			if !ok || subtle.ConstantTimeCompare([]byte(pass), []byte(creds.Password)) != 1 {
+				basicAuthFailed(w, realm)
+				return
+			}
+
+			ctx := WithUser(r.Context(), User{Name: name, Role: users.Role(name), UID: creds.UID})
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// CodeSSOUnavailable - админские ручки не работают, пока SSO не настроен или недоступен
+const CodeSSOUnavailable = "sso_unavailable"
+
+// AdminOnly пускает только администраторов (по данным SSO). Ставится после New.
+// checker == nil - SSO выключен, ручки отвечают 503
+func AdminOnly(log *slog.Logger, checker AdminChecker) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFromContext(r.Context())
+			if !ok {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, resp.Error("unauthorized"))
+
+				return
+			}
+
+			if checker == nil {
+				log.Warn("sso is disabled, admin route is unavailable")
+				render.Status(r, http.StatusServiceUnavailable)
+				render.JSON(w, r, resp.ErrorCode(CodeSSOUnavailable, "sso is not configured, admin routes are unavailable"))
+
+				return
+			}
+
+			if user.UID == 0 {
+				log.Info("user has no sso id", slog.String("user", user.Name))
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error("forbidden"))
+
+				return
+			}
+
+			isAdmin, err := checker.IsAdmin(r.Context(), user.UID)
+			if errors.Is(err, sso.ErrUnavailable) {
+				log.Warn("sso is unavailable", slog.String("user", user.Name), sl.Err(err))
+				render.Status(r, http.StatusServiceUnavailable)
+				render.JSON(w, r, resp.ErrorCode(CodeSSOUnavailable, "sso is unavailable, try again later"))
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to check admin permissions", slog.String("user", user.Name), sl.Err(err))
+				render.Status(r, http.StatusInternalServerError)
+				render.JSON(w, r, resp.Error("failed to check permissions"))
+
+				return
+			}
+
+			if !isAdmin {
+				log.Info("user is not admin", slog.String("user", user.Name))
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, resp.Error("forbidden"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CanManage - менять ссылку может ее владелец или администратор
+func CanManage(user User, owner string) bool {
+	return user.Role == RoleAdmin || user.Name == owner
+}
+
+// UserFromContext - пользователь текущего запроса
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(ctxKey{}).(User)
+
+	return user, ok
+}
+
+// WithUser кладет пользователя в контекст. Используется и в тестах хендлеров без middleware
+func WithUser(ctx context.Context, user User) context.Context {
+	if h, ok := ctx.Value(holderKey{}).(*userHolder); ok {
+		h.user, h.ok = user, true
+	}
+
+	return context.WithValue(ctx, ctxKey{}, user)
+}
+
+// TrackUser - для middleware, которые стоят раньше auth (access log): контекст, который New передает дальше,
+// им не виден. Запрос нужно обработать с возвращенным контекстом, после этого user отдаст пользователя,
+// если он прошел проверку
+func TrackUser(ctx context.Context) (tracked context.Context, user func() (User, bool)) {
+	h := &userHolder{}
+
+	return context.WithValue(ctx, holderKey{}, h), func() (User, bool) {
+		return h.user, h.ok
+	}
+}
+
+func basicAuthFailed(w http.ResponseWriter, realm string) {
+	w.Header().Add("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, realm))
+	w.WriteHeader(http.StatusUnauthorized)
+}
@@ -0,0 +1,196 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/clients/sso"
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestNew(t *testing.T) {
+	users := auth.NewUsers(map[string]auth.Credentials{
+		"admin": {Password: "qwerty", Role: auth.RoleAdmin, UID: 1},
+		"bob":   {Password: "secret"},
+	})
+
+	cases := []struct {
+		name     string
+		user     string
+		password string
+		noAuth   bool
+		status   int
+		want     auth.User
+	}{
+		{name: "Admin", user: "admin", password: "qwerty", status: http.StatusOK, want: auth.User{Name: "admin", Role: auth.RoleAdmin, UID: 1}},
+		{name: "Default role", user: "bob", password: "secret", status: http.StatusOK, want: auth.User{Name: "bob", Role: auth.RoleUser}},
+		{name: "Wrong password", user: "bob", password: "qwerty", status: http.StatusUnauthorized},
+		{name: "Unknown user", user: "alice", password: "secret", status: http.StatusUnauthorized},
+		{name: "No credentials", noAuth: true, status: http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got auth.User
+			handler := auth.New("test", users)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = auth.UserFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if !tc.noAuth {
+				req.SetBasicAuth(tc.user, tc.password)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+			assert.Equal(t, tc.want, got)
+
+			if tc.status == http.StatusUnauthorized {
+				assert.Equal(t, `Basic realm="test"`, rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestUsers_Role(t *testing.T) {
+	users := auth.NewUsers(map[string]auth.Credentials{
+		"admin": {Password: "qwerty", Role: auth.RoleAdmin},
+		"bob":   {Password: "secret"},
+	})
+
+	assert.Equal(t, auth.RoleAdmin, users.Role("admin"))
+	assert.Equal(t, auth.RoleUser, users.Role("bob"))
+	assert.Equal(t, auth.RoleUser, users.Role("unknown"))
+}
+
+func TestCanManage(t *testing.T) {
+	assert.True(t, auth.CanManage(auth.User{Name: "bob", Role: auth.RoleUser}, "bob"))
+	assert.False(t, auth.CanManage(auth.User{Name: "alice", Role: auth.RoleUser}, "bob"))
+	assert.True(t, auth.CanManage(auth.User{Name: "admin", Role: auth.RoleAdmin}, "bob"))
+}
+
+func TestTrackUser(t *testing.T) {
+	users := auth.NewUsers(map[string]auth.Credentials{"bob": {Password: "secret"}})
+	handler := auth.New("test", users)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, pass := range []string{"secret", "wrong"} {
+		ctx, user := auth.TrackUser(context.Background())
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		req.SetBasicAuth("bob", pass)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		got, ok := user()
+		assert.Equal(t, pass == "secret", ok, pass)
+		if ok {
+			assert.Equal(t, "bob", got.Name)
+		}
+	}
+}
+
+func TestUsers_Set(t *testing.T) {
+	users := auth.NewUsers(map[string]auth.Credentials{"bob": {Password: "secret"}})
+	handler := auth.New("test", users)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(user, password string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(user, password)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusOK, do("bob", "secret"))
+
+	// новый список применяется к уже созданному middleware
+	users.Set(map[string]auth.Credentials{"bob": {Password: "new-secret"}, "alice": {Password: "pass"}})
+	require.Equal(t, http.StatusUnauthorized, do("bob", "secret"))
+	require.Equal(t, http.StatusOK, do("bob", "new-secret"))
+	require.Equal(t, http.StatusOK, do("alice", "pass"))
+}
+
+type adminChecker map[int64]bool
+
+func (c adminChecker) IsAdmin(_ context.Context, userID int64) (bool, error) {
+	// 4 - SSO не отвечает
+	if userID == 4 {
+		return false, fmt.Errorf("grpc.IsAdmin: %w", sso.ErrUnavailable)
+	}
+
+	isAdmin, ok := c[userID]
+	if !ok {
+		return false, errors.New("sso error")
+	}
+
+	return isAdmin, nil
+}
+
+func TestAdminOnly(t *testing.T) {
+	checker := adminChecker{1: true, 2: false}
+
+	cases := []struct {
+		name     string
+		user     *auth.User
+		disabled bool // SSO не настроен
+		status   int
+		respCode string
+	}{
+		{name: "Admin", user: &auth.User{Name: "admin", UID: 1}, status: http.StatusOK},
+		{name: "Not admin", user: &auth.User{Name: "bob", UID: 2}, status: http.StatusForbidden},
+		{name: "No sso id", user: &auth.User{Name: "alice"}, status: http.StatusForbidden},
+		{name: "SSO error", user: &auth.User{Name: "eve", UID: 3}, status: http.StatusInternalServerError},
+		{name: "No user", status: http.StatusUnauthorized},
+		{name: "SSO unavailable", user: &auth.User{Name: "admin", UID: 4}, status: http.StatusServiceUnavailable, respCode: auth.CodeSSOUnavailable},
+		{name: "SSO disabled", user: &auth.User{Name: "admin", UID: 1}, disabled: true, status: http.StatusServiceUnavailable, respCode: auth.CodeSSOUnavailable},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var c auth.AdminChecker = checker
+			if tc.disabled {
+				c = nil
+			}
+
+			handler := auth.AdminOnly(slogdiscard.NewDiscardLogger(), c)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+			)
+
+			req := httptest.NewRequest(http.MethodGet, "/audit", nil)
+			if tc.user != nil {
+				req = req.WithContext(auth.WithUser(req.Context(), *tc.user))
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			if tc.respCode != "" {
+				var body resp.Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+				require.Equal(t, tc.respCode, body.Code)
+			}
+		})
+	}
+}
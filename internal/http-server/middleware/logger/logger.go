@@ -1,39 +1,108 @@
 package logger
 
 import (
-	"github.com/go-chi/chi/v5/middleware"
+	"bytes"
+	"encoding/json"
+	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/lib/logger/accesslog"
 )
 
-func New(log *slog.Logger) func(next http.Handler) http.Handler {
+const (
+	combinedTimeFormat = "02/Jan/2006:15:04:05 -0700"
+	ecsVersion         = "8.11.0"
+)
+
+type Options struct {
+	Format string    // по умолчанию accesslog.FormatSlog
+	Out    io.Writer // куда пишутся combined и ecs, nil - stdout. slog пишет в переданный логгер
+	// Redact скрывает секреты в url, query и referer. Для slog тоже: query без ? slogredact не узнает
+	Redact func(string) string
+}
+
+// entry - все, что знаем о запросе после ответа
+type entry struct {
+	start     time.Time
+	duration  time.Duration
+	method    string
+	path      string
+	query     string
+	uri       string // path с query как в запросе
+	proto     string
+	status    int
+	bytes     int
+	remoteIP  string
+	user      string // только прошедший проверку в auth
+	referer   string
+	userAgent string
+	requestID string
+	traceID   string
+}
+
+func New(log *slog.Logger, opts Options) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		log = log.With(
 			slog.String("component", "middleware/logger"),
 		)
 
+		if opts.Format == "" {
+			opts.Format = accesslog.FormatSlog
+		}
+		if opts.Out == nil {
+			opts.Out = os.Stdout
+		}
+		if opts.Redact == nil {
+			opts.Redact = func(s string) string { return s }
+		}
+
 		// будет вызвано 1 раз, а не при каждом входящем запросе
-		log.Info("logger middleware enabled")
+		log.Info("logger middleware enabled", slog.String("format", opts.Format))
+
+		// одна запись - один Write, но не каждый io.Writer безопасен для конкурентной записи
+		var mu sync.Mutex
+		write := func(line []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			_, _ = opts.Out.Write(line)
+		}
 
 		fn := func(w http.ResponseWriter, r *http.Request) {
-			entry := log.With(
-				slog.String("method", r.Method),
-				slog.String("path", r.URL.Path),
-				slog.String("remote_addr", r.RemoteAddr),
-				slog.String("user_agent", r.UserAgent()),
-				slog.String("request_id", middleware.GetReqID(r.Context())),
-			)
 			// дает возможность получить сведения из ответа
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
+			// auth стоит дальше по цепочке, пользователя узнаем после ответа
+			ctx, authUser := auth.TrackUser(r.Context())
+			r = r.WithContext(ctx)
+
 			t1 := time.Now()
 			defer func() {
-				entry.Info("request completed",
-					slog.Int("status", ww.Status()),
-					slog.Int("bytes", ww.BytesWritten()),
-					slog.String("duration", time.Since(t1).String()),
-				)
+				e := newEntry(r, ww, t1)
+				if user, ok := authUser(); ok {
+					e.user = user.Name
+				}
+
+				switch opts.Format {
+				case accesslog.FormatCombined:
+					write(combined(e, opts.Redact))
+				case accesslog.FormatECS:
+					write(ecs(e, opts.Redact))
+				default:
+					// с контекстом, чтобы в запись попал trace_id
+					log.InfoContext(r.Context(), "request completed", attrs(e, opts.Redact)...)
+				}
 			}()
 
 			next.ServeHTTP(ww, r)
@@ -42,3 +111,198 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 		return http.HandlerFunc(fn)
 	}
 }
+
+func newEntry(r *http.Request, ww middleware.WrapResponseWriter, start time.Time) entry {
+	e := entry{
+		start:     start,
+		duration:  time.Since(start),
+		method:    r.Method,
+		path:      r.URL.Path,
+		query:     r.URL.RawQuery,
+		uri:       r.URL.RequestURI(),
+		proto:     r.Proto,
+		status:    ww.Status(),
+		bytes:     ww.BytesWritten(),
+		remoteIP:  remoteIP(r),
+		referer:   r.Referer(),
+		userAgent: r.UserAgent(),
+		requestID: middleware.GetReqID(r.Context()),
+	}
+
+	// ответ без WriteHeader - это 200
+	if e.status == 0 {
+		e.status = http.StatusOK
+	}
+
+	if sc := trace.SpanContextFromContext(r.Context()); sc.IsValid() {
+		e.traceID = sc.TraceID().String()
+	}
+
+	return e
+}
+
+// RealIP кладет в RemoteAddr ip без порта
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func attrs(e entry, redact func(string) string) []any {
+	return []any{
+		slog.String("method", e.method),
+		slog.String("path", e.path),
+		slog.String("query", redactQuery(e.query, redact)),
+		slog.String("proto", e.proto),
+		slog.String("remote_addr", e.remoteIP),
+		slog.String("user", e.user),
+		slog.String("referer", redact(e.referer)),
+		slog.String("user_agent", e.userAgent),
+		slog.String("request_id", e.requestID),
+		slog.Int("status", e.status),
+		slog.Int("bytes", e.bytes),
+		slog.Float64("duration_ms", float64(e.duration)/float64(time.Millisecond)),
+	}
+}
+
+// redact работает с url, поэтому отдаем ему query с ?
+func redactQuery(query string, redact func(string) string) string {
+	if query == "" {
+		return ""
+	}
+
+	return strings.TrimPrefix(redact("?"+query), "?")
+}
+
+// combined - 127.0.0.1 - bob [02/Jan/2024:15:04:05 +0000] "GET /abc?x=1 HTTP/1.1" 302 0 "-" "curl/8.0"
+func combined(e entry, redact func(string) string) []byte {
+	var b bytes.Buffer
+
+	b.WriteString(dash(e.remoteIP))
+	b.WriteString(" - ")
+	b.WriteString(dash(e.user))
+	b.WriteString(" [")
+	b.WriteString(e.start.Format(combinedTimeFormat))
+	b.WriteString("] ")
+	b.WriteString(strconv.Quote(e.method + " " + redact(e.uri) + " " + e.proto))
+	b.WriteByte(' ')
+	b.WriteString(strconv.Itoa(e.status))
+	b.WriteByte(' ')
+	if e.bytes > 0 {
+		b.WriteString(strconv.Itoa(e.bytes))
+	} else {
+		b.WriteByte('-')
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.Quote(dash(redact(e.referer))))
+	b.WriteByte(' ')
+	b.WriteString(strconv.Quote(dash(e.userAgent)))
+	b.WriteByte('\n')
+
+	return b.Bytes()
+}
+
+// пустые поля в combined - "-"
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// поля ECS, которые заполняем. Пустые не пишем
+type ecsEntry struct {
+	Timestamp string `json:"@timestamp"`
+	Message   string `json:"message"`
+	ECS       struct {
+		Version string `json:"version"`
+	} `json:"ecs"`
+	Log struct {
+		Level string `json:"level"`
+	} `json:"log"`
+	Event struct {
+		Dataset  string `json:"dataset"`
+		Duration int64  `json:"duration"` // в наносекундах
+	} `json:"event"`
+	HTTP struct {
+		Version string `json:"version,omitempty"`
+		Request struct {
+			ID       string `json:"id,omitempty"`
+			Method   string `json:"method"`
+			Referrer string `json:"referrer,omitempty"`
+		} `json:"request"`
+		Response struct {
+			StatusCode int `json:"status_code"`
+			Body       struct {
+				Bytes int `json:"bytes"`
+			} `json:"body"`
+		} `json:"response"`
+	} `json:"http"`
+	URL struct {
+		Original string `json:"original"`
+		Path     string `json:"path"`
+		Query    string `json:"query,omitempty"`
+	} `json:"url"`
+	Client struct {
+		IP string `json:"ip,omitempty"`
+	} `json:"client"`
+	User      *ecsUser `json:"user,omitempty"`
+	UserAgent struct {
+		Original string `json:"original,omitempty"`
+	} `json:"user_agent"`
+	Trace *ecsTrace `json:"trace,omitempty"`
+}
+
+type ecsUser struct {
+	Name string `json:"name"`
+}
+
+type ecsTrace struct {
+	ID string `json:"id"`
+}
+
+func ecs(e entry, redact func(string) string) []byte {
+	var le ecsEntry
+
+	le.Timestamp = e.start.UTC().Format(time.RFC3339Nano)
+	le.Message = e.method + " " + e.path + " " + strconv.Itoa(e.status)
+	le.ECS.Version = ecsVersion
+	le.Log.Level = "info"
+	le.Event.Dataset = "url-shortener.access"
+	le.Event.Duration = e.duration.Nanoseconds()
+
+	// HTTP/1.1 -> 1.1
+	if len(e.proto) > len("HTTP/") {
+		le.HTTP.Version = e.proto[len("HTTP/"):]
+	}
+	le.HTTP.Request.ID = e.requestID
+	le.HTTP.Request.Method = e.method
+	le.HTTP.Request.Referrer = redact(e.referer)
+	le.HTTP.Response.StatusCode = e.status
+	le.HTTP.Response.Body.Bytes = e.bytes
+
+	le.URL.Original = redact(e.uri)
+	le.URL.Path = e.path
+	le.URL.Query = redactQuery(e.query, redact)
+
+	le.Client.IP = e.remoteIP
+	if e.user != "" {
+		le.User = &ecsUser{Name: e.user}
+	}
+	le.UserAgent.Original = e.userAgent
+	if e.traceID != "" {
+		le.Trace = &ecsTrace{ID: e.traceID}
+	}
+
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	// ошибки быть не может: только строки и числа
+	_ = enc.Encode(le)
+
+	return b.Bytes()
+}
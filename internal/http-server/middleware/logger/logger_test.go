@@ -0,0 +1,143 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	mwLogger "url-shortener/internal/http-server/middleware/logger"
+	"url-shortener/internal/lib/logger/accesslog"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+// как slogredact, но только для token
+var tokenRe = regexp.MustCompile(`([?&]token=)[^&]*`)
+
+func redact(s string) string {
+	return tokenRe.ReplaceAllString(s, "${1}***")
+}
+
+// serve - user пусто: запрос не прошел auth
+func serve(t *testing.T, log *slog.Logger, opts mwLogger.Options, user string) {
+	t.Helper()
+
+	handler := middleware.RequestID(mwLogger.New(log, opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user != "" {
+			// так делает auth.New после проверки пароля
+			_ = auth.WithUser(r.Context(), auth.User{Name: user})
+		}
+
+		w.WriteHeader(http.StatusFound)
+		_, _ = w.Write([]byte("found"))
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/abc?token=secret&x=1", nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	req.Header.Set("Referer", "https://example.com/page?token=ref")
+	req.Header.Set("User-Agent", `curl/8.0 "quoted"`)
+	// заголовок ничего не значит, пока auth не проверил пароль
+	req.SetBasicAuth("mallory", "wrong")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestNew_Combined(t *testing.T) {
+	cases := []struct {
+		name     string
+		user     string
+		wantUser string
+	}{
+		{name: "Authenticated", user: "bob", wantUser: "bob"},
+		{name: "Not authenticated", wantUser: "-"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out bytes.Buffer
+			serve(t, slogdiscard.NewDiscardLogger(), mwLogger.Options{Format: accesslog.FormatCombined, Out: &out, Redact: redact}, tc.user)
+
+			re := regexp.MustCompile(`^10\.0\.0\.1 - ` + tc.wantUser + ` \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] ` +
+				regexp.QuoteMeta(`"GET /abc?token=***&x=1 HTTP/1.1" 302 5 "https://example.com/page?token=***" "curl/8.0 \"quoted\""`) + "\n$")
+			assert.Regexp(t, re, out.String())
+		})
+	}
+}
+
+func TestNew_ECS(t *testing.T) {
+	var out bytes.Buffer
+	serve(t, slogdiscard.NewDiscardLogger(), mwLogger.Options{Format: accesslog.FormatECS, Out: &out, Redact: redact}, "bob")
+
+	require.Equal(t, 1, strings.Count(out.String(), "\n"))
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+
+	assert.NotEmpty(t, got["@timestamp"])
+	assert.Equal(t, "GET /abc 302", got["message"])
+
+	event := got["event"].(map[string]any)
+	assert.IsType(t, float64(0), event["duration"])
+
+	httpField := got["http"].(map[string]any)
+	assert.Equal(t, "1.1", httpField["version"])
+	assert.Equal(t, "https://example.com/page?token=***", httpField["request"].(map[string]any)["referrer"])
+	assert.NotEmpty(t, httpField["request"].(map[string]any)["id"])
+	assert.Equal(t, map[string]any{"status_code": float64(302), "body": map[string]any{"bytes": float64(5)}}, httpField["response"])
+
+	assert.Equal(t, map[string]any{"original": "/abc?token=***&x=1", "path": "/abc", "query": "token=***&x=1"}, got["url"])
+	assert.Equal(t, map[string]any{"ip": "10.0.0.1"}, got["client"])
+	assert.Equal(t, map[string]any{"name": "bob"}, got["user"])
+	assert.Equal(t, map[string]any{"original": `curl/8.0 "quoted"`}, got["user_agent"])
+}
+
+func TestNew_Slog(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	// combined и ecs в Out, slog - в логгер
+	var out bytes.Buffer
+	serve(t, log, mwLogger.Options{Out: &out, Redact: redact}, "bob")
+	require.Empty(t, out.String())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2) // logger middleware enabled + request completed
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &got))
+
+	assert.Equal(t, "request completed", got["msg"])
+	assert.Equal(t, "middleware/logger", got["component"])
+	// token - первый параметр, без ? его не узнать
+	assert.Equal(t, "token=***&x=1", got["query"])
+	assert.NotContains(t, lines[1], "secret")
+	assert.Equal(t, "HTTP/1.1", got["proto"])
+	assert.Equal(t, "bob", got["user"])
+	assert.Equal(t, "https://example.com/page?token=***", got["referer"])
+	assert.Equal(t, float64(302), got["status"])
+	assert.Equal(t, float64(5), got["bytes"])
+	assert.IsType(t, float64(0), got["duration_ms"])
+}
+
+func TestNew_ECS_NotAuthenticated(t *testing.T) {
+	var out bytes.Buffer
+	serve(t, slogdiscard.NewDiscardLogger(), mwLogger.Options{Format: accesslog.FormatECS, Out: &out}, "")
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+
+	assert.NotContains(t, got, "user")
+}
@@ -0,0 +1,33 @@
+package metrics_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	mwMetrics "url-shortener/internal/http-server/middleware/metrics"
+	"url-shortener/internal/lib/metrics"
+)
+
+func TestMiddleware(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(mwMetrics.New())
+	router.Route("/test", func(r chi.Router) {
+		r.Get("/{alias}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusFound)
+		})
+	})
+
+	for _, path := range []string{"/test/a1", "/test/a2", "/missing"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// одна серия на шаблон роута, а не на каждый alias
+	require.Equal(t, 2.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("/test/{alias}", http.MethodGet, "302")))
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues("unmatched", http.MethodGet, "404")))
+	require.Equal(t, 2, testutil.CollectAndCount(metrics.HTTPDuration))
+}
@@ -0,0 +1,198 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	resp "url-shortener/internal/lib/api/response"
+)
+
+// через сколько неиспользуемый (и уже полный) bucket можно удалить
+const sweepInterval = time.Minute
+
+// Limiter - token bucket на каждый ключ (пользователь, ip и тд). rps <= 0 - без ограничений
+type Limiter struct {
+	mu        sync.Mutex
+	rps       float64 // сколько токенов добавляется в секунду
+	burst     int     // размер bucket'а
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Result - результат проверки лимита, нужен для заголовков ответа
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration // через сколько появится следующий токен
+	Reset      time.Duration // через сколько bucket заполнится полностью
+}
+
+func NewLimiter(rps float64, burst int) *Limiter {
+	l := &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+	l.SetLimit(rps, burst)
+
+	return l
+}
+
+// SetLimit меняет лимит на лету. Накопленные токены сохраняются, но не больше нового burst
+func (l *Limiter) SetLimit(rps float64, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rps = rps
+	l.burst = burst
+	if rps <= 0 {
+		// без ограничений bucket'ы не нужны
+		l.buckets = make(map[string]*bucket)
+	}
+}
+
+// Allow пробует забрать один токен из bucket'а ключа.
+// Без ограничений Result.Limit = 0
+func (l *Limiter) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rps <= 0 {
+		return Result{Allowed: true}
+	}
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+
+	// пополняем bucket за прошедшее время
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rps)
+	b.last = now
+
+	res := Result{Limit: l.burst}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.duration(1 - b.tokens)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = l.duration(float64(l.burst) - b.tokens)
+
+	return res
+}
+
+// сколько ждать, пока накопится n токенов
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rps * float64(time.Second))
+}
+
+// удаляем заполненные bucket'ы, чтобы map не росла бесконечно
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rps >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// KeyFunc - по чему считаем лимит для запроса
+type KeyFunc func(r *http.Request) string
+
+// ByUser - лимит на пользователя. Рассчитываем, что перед этим отработал auth
+func ByUser(r *http.Request) string {
+	user, _ := auth.UserFromContext(r.Context())
+
+	return user.Name
+}
+
+// ByIP - лимит на ip клиента. Рассчитываем, что перед этим отработал middleware.RealIP
+func ByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RealIP кладет в RemoteAddr ip без порта
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// New - middleware с ограничением частоты запросов. Если limiter == nil, то ограничений нет
+func New(log *slog.Logger, limiter *Limiter, keyFn KeyFunc) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+
+		log := log.With(
+			slog.String("component", "middleware/ratelimit"),
+		)
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			key := keyFn(r)
+			res := limiter.Allow(key)
+			if res.Limit == 0 {
+				// лимит выключен
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+
+			if !res.Allowed {
+				log.Info("rate limit exceeded",
+					slog.String("key", key),
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+				)
+
+				w.Header().Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, resp.Error("too many requests"))
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// округляем вверх, чтобы клиент не пришел раньше времени
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
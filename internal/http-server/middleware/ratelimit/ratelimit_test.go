@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := NewLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		res := l.Allow("user")
+		require.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	res := l.Allow("user")
+	require.False(t, res.Allowed)
+	assert.Equal(t, 500*time.Millisecond, res.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, res.Reset)
+
+	// другой ключ - свой bucket
+	require.True(t, l.Allow("other").Allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	require.True(t, l.Allow("user").Allowed)
+	require.False(t, l.Allow("user").Allowed)
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := NewLimiter(1, 1)
+	l.now = func() time.Time { return now }
+
+	l.Allow("a")
+	require.Len(t, l.buckets, 1)
+
+	now = now.Add(2 * sweepInterval)
+	l.Allow("b")
+	require.Len(t, l.buckets, 1)
+	require.Contains(t, l.buckets, "b")
+}
+
+func TestLimiter_SetLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := NewLimiter(1, 5)
+	l.now = func() time.Time { return now }
+
+	require.True(t, l.Allow("user").Allowed)
+
+	// накопленные токены обрезаются до нового burst
+	l.SetLimit(1, 1)
+	res := l.Allow("user")
+	require.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Limit)
+	require.False(t, l.Allow("user").Allowed)
+
+	// без ограничений
+	l.SetLimit(0, 0)
+	res = l.Allow("user")
+	require.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Limit)
+	require.Empty(t, l.buckets)
+
+	// лимит снова включен, bucket начинается полным
+	l.SetLimit(1, 2)
+	require.True(t, l.Allow("user").Allowed)
+	require.True(t, l.Allow("user").Allowed)
+	require.False(t, l.Allow("user").Allowed)
+}
+
+func TestMiddleware(t *testing.T) {
+	cases := []struct {
+		name       string
+		limiter    *Limiter
+		requests   int
+		wantStatus int
+		wantRetry  string
+		wantLimit  string
+	}{
+		{
+			name:       "Allowed",
+			limiter:    NewLimiter(1, 2),
+			requests:   2,
+			wantStatus: http.StatusOK,
+			wantLimit:  "2",
+		},
+		{
+			name:       "Too many requests",
+			limiter:    NewLimiter(1, 2),
+			requests:   3,
+			wantStatus: http.StatusTooManyRequests,
+			wantRetry:  "1",
+			wantLimit:  "2",
+		},
+		{
+			name:       "Disabled",
+			requests:   10,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Zero rps",
+			limiter:    NewLimiter(0, 0),
+			requests:   10,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := New(slogdiscard.NewDiscardLogger(), tc.limiter, ByIP)(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+			)
+
+			var rr *httptest.ResponseRecorder
+			for i := 0; i < tc.requests; i++ {
+				req := httptest.NewRequest(http.MethodGet, "/alias", nil)
+				req.RemoteAddr = "10.0.0.1:1234"
+
+				rr = httptest.NewRecorder()
+				handler.ServeHTTP(rr, req)
+			}
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantRetry, rr.Header().Get("Retry-After"))
+
+			assert.Equal(t, tc.wantLimit, rr.Header().Get("X-RateLimit-Limit"))
+			if tc.wantLimit != "" {
+				assert.Equal(t, "0", rr.Header().Get("X-RateLimit-Remaining"))
+			}
+		})
+	}
+}
+
+func TestByIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	req.RemoteAddr = "10.0.0.1:1234"
+	assert.Equal(t, "10.0.0.1", ByIP(req))
+
+	// после middleware.RealIP
+	req.RemoteAddr = "10.0.0.2"
+	assert.Equal(t, "10.0.0.2", ByIP(req))
+}
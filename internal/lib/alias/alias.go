@@ -0,0 +1,136 @@
+package alias
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	ErrReserved = errors.New("alias is reserved")
+	ErrBanned   = errors.New("alias contains a banned word")
+)
+
+// ValidationTag - тег валидатора: только латиница, цифры, "-" и "_"
+const ValidationTag = "alias"
+
+var aliasRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// RegisterValidation добавляет в валидатор тег alias
+func RegisterValidation(v *validator.Validate) error {
+	return v.RegisterValidation(ValidationTag, func(fl validator.FieldLevel) bool {
+		return aliasRegexp.MatchString(fl.Field().String())
+	})
+}
+
+// NewValidator - validator.New() с тегом alias
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	if err := RegisterValidation(v); err != nil {
+		// возможно только при пустом теге или nil функции
+		panic(err)
+	}
+
+	return v
+}
+
+// Normalize приводит alias к виду, в котором они сравниваются без учета регистра:
+// обрезает пробелы, делает case folding и Unicode NFC. ABC123 и abc123 - один alias
+func Normalize(alias string) string {
+	return norm.NFC.String(cases.Fold().String(strings.TrimSpace(alias)))
+}
+
+// Policy - какие alias нельзя занимать: зарезервированные слова (пути роутера и тд) и нецензурные слова
+type Policy struct {
+	mu       sync.RWMutex
+	reserved map[string]struct{}
+	banned   []string
+}
+
+// NewPolicy
+// bannedWordsPath - файл со словами, по одному на строку, # - комментарий. Пустой путь - без проверки
+func NewPolicy(reserved []string, bannedWordsPath string) (*Policy, error) {
+	const op = "alias.NewPolicy"
+
+	p := &Policy{
+		reserved: make(map[string]struct{}, len(reserved)),
+	}
+	p.Reserve(reserved...)
+
+	if bannedWordsPath != "" {
+		banned, err := loadWords(bannedWordsPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		p.banned = banned
+	}
+
+	return p, nil
+}
+
+// Reserve добавляет зарезервированные слова. Можно вызывать после создания, например, когда известны все роуты
+func (p *Policy) Reserve(words ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			p.reserved[w] = struct{}{}
+		}
+	}
+}
+
+// Check - alias не должен совпадать с зарезервированным словом и содержать нецензурных слов
+func (p *Policy) Check(alias string) error {
+	lower := strings.ToLower(alias)
+
+	p.mu.RLock()
+	_, reserved := p.reserved[lower]
+	p.mu.RUnlock()
+
+	if reserved {
+		return fmt.Errorf("%w: %s", ErrReserved, alias)
+	}
+
+	// разделители не спасают: "b-a-d" тоже плохо
+	compact := strings.NewReplacer("-", "", "_", "").Replace(lower)
+	for _, word := range p.banned {
+		if strings.Contains(compact, word) {
+			return ErrBanned
+		}
+	}
+
+	return nil
+}
+
+func loadWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var words []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.ToLower(strings.TrimSpace(line)); line != "" {
+			words = append(words, line)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return words, nil
+}
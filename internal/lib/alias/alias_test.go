@@ -0,0 +1,90 @@
+package alias_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/alias"
+)
+
+func TestRegisterValidation(t *testing.T) {
+	v := validator.New()
+	require.NoError(t, alias.RegisterValidation(v))
+
+	cases := []struct {
+		alias string
+		valid bool
+	}{
+		{alias: "abc123", valid: true},
+		{alias: "my-alias_2", valid: true},
+		{alias: "with space", valid: false},
+		{alias: "slash/alias", valid: false},
+		{alias: "кириллица", valid: false},
+		{alias: "query?x=1", valid: false},
+	}
+
+	for _, tc := range cases {
+		err := v.Var(tc.alias, alias.ValidationTag)
+		if tc.valid {
+			require.NoError(t, err, tc.alias)
+		} else {
+			require.Error(t, err, tc.alias)
+		}
+	}
+}
+
+func TestPolicy_Check(t *testing.T) {
+	banned := filepath.Join(t.TempDir(), "banned.txt")
+	require.NoError(t, os.WriteFile(banned, []byte("# test words\nbadword\nnasty\n"), 0o600))
+
+	policy, err := alias.NewPolicy([]string{"admin", "Login"}, banned)
+	require.NoError(t, err)
+
+	// пути роутера добавляются после регистрации роутов
+	policy.Reserve("url", "audit")
+
+	cases := []struct {
+		name    string
+		alias   string
+		wantErr error
+	}{
+		{name: "Allowed", alias: "google"},
+		{name: "Reserved", alias: "admin", wantErr: alias.ErrReserved},
+		{name: "Reserved case insensitive", alias: "LOGIN", wantErr: alias.ErrReserved},
+		{name: "Reserved route", alias: "url", wantErr: alias.ErrReserved},
+		{name: "Reserved word as part", alias: "admin-page"},
+		{name: "Banned", alias: "badword", wantErr: alias.ErrBanned},
+		{name: "Banned inside", alias: "my-BadWord-link", wantErr: alias.ErrBanned},
+		{name: "Banned with separators", alias: "nas_ty", wantErr: alias.ErrBanned},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.alias)
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"ABC123":     "abc123",
+		" abc123 ":   "abc123",
+		"Straße":     "strasse",
+		"cafe\u0301": "caf\u00e9", // e + combining acute -> é
+	}
+
+	for in, want := range cases {
+		require.Equal(t, want, alias.Normalize(in), in)
+	}
+}
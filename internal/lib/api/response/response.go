@@ -9,6 +9,7 @@ import (
 type Response struct {
 	Status string `json:"status"`
 	Error  string `json:"error,omitempty"`
+	Code   string `json:"code,omitempty"` // машиночитаемый код ошибки
 }
 
 const (
@@ -29,6 +30,14 @@ func Error(msg string) Response {
 	}
 }
 
+func ErrorCode(code string, msg string) Response {
+	return Response{
+		Status: StatusError,
+		Error:  msg,
+		Code:   code,
+	}
+}
+
 func ValidationError(errs validator.ValidationErrors) Response {
 	var errMsgs []string
 
@@ -38,6 +47,10 @@ func ValidationError(errs validator.ValidationErrors) Response {
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is a required field", err.Field()))
 		case "url":
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not a valid URL", err.Field()))
+		case "alias":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s may contain only latin letters, digits, '-' and '_'", err.Field()))
+		case "max":
+			errMsgs = append(errMsgs, fmt.Sprintf("field %s must be at most %s characters long", err.Field(), err.Param()))
 		default:
 			errMsgs = append(errMsgs, fmt.Sprintf("field %s is not valid", err.Field()))
 		}
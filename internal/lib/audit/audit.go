@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"url-shortener/internal/http-server/middleware/auth"
+	"url-shortener/internal/storage"
+)
+
+const (
+	ActionCreate  = "create"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionRename  = "rename"
+	ActionUpdate  = "update"
+)
+
+// ActorSystem - изменения, которые сделал сам сервис, а не пользователь
+const ActorSystem = "system"
+
+// NewEntry - запись журнала для текущего запроса: пользователь из auth и id запроса
+func NewEntry(r *http.Request, action string, alias string, oldValue string, newValue string) storage.AuditEntry {
+	user, _ := auth.UserFromContext(r.Context())
+
+	return storage.AuditEntry{
+		Actor:     user.Name,
+		Action:    action,
+		Alias:     alias,
+		OldValue:  oldValue,
+		NewValue:  newValue,
+		RequestID: middleware.GetReqID(r.Context()),
+		CreatedAt: time.Now(),
+	}
+}
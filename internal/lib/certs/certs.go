@@ -0,0 +1,303 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+var (
+	ErrNoCertificate  = errors.New("certificate is not configured")
+	ErrInvalidCA      = errors.New("no certificates found in CA bundle")
+	ErrUnknownVersion = errors.New("unknown TLS version")
+	ErrUnknownCipher  = errors.New("unknown or insecure cipher suite")
+)
+
+// Store хранит сертификат с ключом и CA bundle, прочитанные из файлов, и перечитывает их при изменении.
+// tls.Config получает сертификаты через колбэки, поэтому новые соединения сразу используют новые файлы.
+// Любой из путей может быть пустым: без CA используются системные корневые сертификаты
+type Store struct {
+	log *slog.Logger
+
+	certPath string
+	keyPath  string
+	caPath   string
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	roots    *x509.CertPool
+	modTimes map[string]time.Time
+}
+
+func New(log *slog.Logger, certPath string, keyPath string, caPath string) (*Store, error) {
+	const op = "certs.New"
+
+	if (certPath == "") != (keyPath == "") {
+		return nil, fmt.Errorf("%s: certificate and key must be set together", op)
+	}
+
+	s := &Store{
+		log:      log.With(slog.String("component", "certs")),
+		certPath: certPath,
+		keyPath:  keyPath,
+		caPath:   caPath,
+		modTimes: make(map[string]time.Time),
+	}
+
+	if err := s.Reload(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// Reload перечитывает файлы. При ошибке остаются старые сертификаты
+func (s *Store) Reload() error {
+	const op = "certs.Reload"
+
+	modTimes := make(map[string]time.Time)
+
+	cert, roots, err := s.load(modTimes)
+	if err != nil {
+		// запоминаем время изменения битого файла, чтобы не перечитывать его до следующего изменения
+		s.mu.Lock()
+		for path, t := range modTimes {
+			s.modTimes[path] = t
+		}
+		s.mu.Unlock()
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	s.mu.Lock()
+	s.cert = cert
+	s.roots = roots
+	s.modTimes = modTimes
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *Store) load(modTimes map[string]time.Time) (*tls.Certificate, *x509.CertPool, error) {
+	var cert *tls.Certificate
+	if s.certPath != "" {
+		certPEM, err := readFile(s.certPath, modTimes)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		keyPEM, err := readFile(s.keyPath, modTimes)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		pair, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", s.certPath, err)
+		}
+		cert = &pair
+	}
+
+	var roots *x509.CertPool
+	if s.caPath != "" {
+		caPEM, err := readFile(s.caPath, modTimes)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caPEM) {
+			return nil, nil, fmt.Errorf("%s: %w", s.caPath, ErrInvalidCA)
+		}
+	}
+
+	return cert, roots, nil
+}
+
+func readFile(path string, modTimes map[string]time.Time) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	modTimes[path] = info.ModTime()
+
+	return os.ReadFile(path)
+}
+
+// Watch раз в interval проверяет, изменились ли файлы, и перечитывает их
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.changed() {
+				continue
+			}
+
+			if err := s.Reload(); err != nil {
+				s.log.Error("failed to reload certificates", sl.Err(err))
+				continue
+			}
+
+			s.log.Info("certificates reloaded")
+		}
+	}
+}
+
+func (s *Store) changed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, path := range []string{s.certPath, s.keyPath, s.caPath} {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			// пусть Reload залогирует ошибку
+			return true
+		}
+
+		if !info.ModTime().Equal(s.modTimes[path]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Certificate - текущий сертификат
+func (s *Store) Certificate() (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.cert == nil {
+		return nil, ErrNoCertificate
+	}
+
+	return s.cert, nil
+}
+
+// RootCAs - текущий CA bundle, nil - системные корневые сертификаты
+func (s *Store) RootCAs() *x509.CertPool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.roots
+}
+
+// ServerConfig - tls.Config для сервера: сертификат берется из Store при каждом подключении
+func (s *Store) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return s.Certificate()
+		},
+	}
+}
+
+// ClientConfig - tls.Config для клиента: сертификат для mTLS, если задан, и проверка сервера по CA из файла.
+// serverName - имя в сертификате сервера. Пусто - имя из адреса подключения, для ip адреса имя обязательно
+func (s *Store) ClientConfig(serverName string) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+
+	if s.certPath != "" {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return s.Certificate()
+		}
+	}
+
+	if s.caPath != "" {
+		// RootCAs в tls.Config не обновить, поэтому стандартную проверку выключаем и проверяем цепочку сами с актуальным CA.
+		// Имя сервера проверяется так же, как в стандартной проверке
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return s.verifyServer(cs, serverName)
+		}
+	}
+
+	return cfg
+}
+
+func (s *Store) verifyServer(cs tls.ConnectionState, serverName string) error {
+	const op = "certs.verifyServer"
+
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("%s: server has no certificate", op)
+	}
+
+	// для ip в SNI ничего не отправляется и cs.ServerName пустой, а пустое имя x509 не проверяет
+	if serverName == "" {
+		serverName = cs.ServerName
+	}
+	if serverName == "" {
+		return fmt.Errorf("%s: server name is unknown", op)
+	}
+
+	opts := x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         s.RootCAs(),
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	if _, err := cs.PeerCertificates[0].Verify(opts); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ParseVersion - версия TLS из конфига: 1.2 или 1.3
+func ParseVersion(version string) (uint16, error) {
+	switch version {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+
+	return 0, fmt.Errorf("%w: %s", ErrUnknownVersion, version)
+}
+
+// ParseCipherSuites - наборы шифров по названиям из crypto/tls, например TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+// Небезопасные наборы (tls.InsecureCipherSuites) не принимаем
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCipher, name)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,301 @@
+package certs_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/certs"
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newCA(t *testing.T, name string) testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue выпускает сертификат для dnsName, возвращает PEM сертификата и ключа
+func (ca testCA) issue(t *testing.T, dnsName string) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, content []byte, modTime time.Time) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(path, content, 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+// handshake соединяет клиента и сервер в памяти, возвращает ошибку клиента
+func handshake(t *testing.T, clientCfg *tls.Config, serverCfg *tls.Config) error {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- tls.Server(serverConn, serverCfg).Handshake()
+	}()
+
+	err := tls.Client(clientConn, clientCfg).Handshake()
+	if err != nil {
+		clientConn.Close()
+		<-serverErr
+
+		return err
+	}
+
+	return <-serverErr
+}
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t, "ca")
+	certPEM, keyPEM := ca.issue(t, "sso.local")
+
+	caPath := filepath.Join(dir, "ca.pem")
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	garbagePath := filepath.Join(dir, "garbage.pem")
+	writeFile(t, caPath, ca.pem, time.Now())
+	writeFile(t, certPath, certPEM, time.Now())
+	writeFile(t, keyPath, keyPEM, time.Now())
+	writeFile(t, garbagePath, []byte("not a certificate"), time.Now())
+
+	cases := []struct {
+		name     string
+		certPath string
+		keyPath  string
+		caPath   string
+		wantErr  bool
+	}{
+		{name: "Nothing"},
+		{name: "CA only", caPath: caPath},
+		{name: "Certificate and CA", certPath: certPath, keyPath: keyPath, caPath: caPath},
+		{name: "Certificate without key", certPath: certPath, wantErr: true},
+		{name: "Key mismatch", certPath: certPath, keyPath: caPath, wantErr: true},
+		{name: "Invalid CA", caPath: garbagePath, wantErr: true},
+		{name: "Missing file", caPath: filepath.Join(dir, "missing.pem"), wantErr: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := certs.New(slogdiscard.NewDiscardLogger(), tc.certPath, tc.keyPath, tc.caPath)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestStore_ClientConfig(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t, "ca")
+	otherCA := newCA(t, "other")
+
+	serverCertPEM, serverKeyPEM := ca.issue(t, "sso.local")
+	serverCert, err := tls.X509KeyPair(serverCertPEM, serverKeyPEM)
+	require.NoError(t, err)
+
+	clientCertPEM, clientKeyPEM := ca.issue(t, "url-shortener")
+	caPath := filepath.Join(dir, "ca.pem")
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	writeFile(t, caPath, ca.pem, time.Now())
+	writeFile(t, certPath, clientCertPEM, time.Now())
+	writeFile(t, keyPath, clientKeyPEM, time.Now())
+
+	store, err := certs.New(slogdiscard.NewDiscardLogger(), certPath, keyPath, caPath)
+	require.NoError(t, err)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	serverCfg := &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+
+	t.Run("mTLS", func(t *testing.T) {
+		require.NoError(t, handshake(t, store.ClientConfig("sso.local"), serverCfg))
+	})
+
+	t.Run("Wrong server name", func(t *testing.T) {
+		require.Error(t, handshake(t, store.ClientConfig("evil.local"), serverCfg))
+	})
+
+	t.Run("Unknown server name", func(t *testing.T) {
+		// для ip адреса SNI пустой, без server_name проверить сервер нельзя
+		cfg := store.ClientConfig("")
+		cfg.ServerName = "127.0.0.1"
+		require.Error(t, handshake(t, cfg, serverCfg))
+	})
+
+	t.Run("Server signed by other CA", func(t *testing.T) {
+		otherCertPEM, otherKeyPEM := otherCA.issue(t, "sso.local")
+		otherCert, err := tls.X509KeyPair(otherCertPEM, otherKeyPEM)
+		require.NoError(t, err)
+
+		cfg := serverCfg.Clone()
+		cfg.Certificates = []tls.Certificate{otherCert}
+		require.Error(t, handshake(t, store.ClientConfig("sso.local"), cfg))
+	})
+}
+
+func TestStore_Watch(t *testing.T) {
+	dir := t.TempDir()
+	oldCA := newCA(t, "old")
+	newCA := newCA(t, "new")
+
+	caPath := filepath.Join(dir, "ca.pem")
+	writeFile(t, caPath, oldCA.pem, time.Now())
+
+	store, err := certs.New(slogdiscard.NewDiscardLogger(), "", "", caPath)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.Watch(ctx, 10*time.Millisecond)
+
+	certPEM, keyPEM := newCA.issue(t, "sso.local")
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	serverCfg := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	// клиент создан до смены CA, но проверяет сервер по актуальному
+	clientCfg := store.ClientConfig("sso.local")
+	require.Error(t, handshake(t, clientCfg, serverCfg))
+
+	// битый файл не ломает текущие сертификаты
+	writeFile(t, caPath, []byte("broken"), time.Now().Add(time.Second))
+	time.Sleep(50 * time.Millisecond)
+	require.Error(t, handshake(t, clientCfg, serverCfg))
+
+	writeFile(t, caPath, newCA.pem, time.Now().Add(2*time.Second))
+	require.Eventually(t, func() bool {
+		return handshake(t, clientCfg, serverCfg) == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStore_ServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	oldCA := newCA(t, "old")
+	newCA := newCA(t, "new")
+
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	certPEM, keyPEM := oldCA.issue(t, "sho.rt")
+	writeFile(t, certPath, certPEM, time.Now())
+	writeFile(t, keyPath, keyPEM, time.Now())
+
+	store, err := certs.New(slogdiscard.NewDiscardLogger(), certPath, keyPath, "")
+	require.NoError(t, err)
+
+	clientCfg := func(ca testCA) *tls.Config {
+		roots := x509.NewCertPool()
+		roots.AddCert(ca.cert)
+
+		return &tls.Config{RootCAs: roots, ServerName: "sho.rt"}
+	}
+
+	serverCfg := store.ServerConfig()
+	require.NoError(t, handshake(t, clientCfg(oldCA), serverCfg))
+
+	// новый сертификат подхватывается без пересоздания tls.Config
+	certPEM, keyPEM = newCA.issue(t, "sho.rt")
+	writeFile(t, certPath, certPEM, time.Now().Add(time.Second))
+	writeFile(t, keyPath, keyPEM, time.Now().Add(time.Second))
+	require.NoError(t, store.Reload())
+
+	require.NoError(t, handshake(t, clientCfg(newCA), serverCfg))
+	require.Error(t, handshake(t, clientCfg(oldCA), serverCfg))
+}
+
+func TestParseVersion(t *testing.T) {
+	v, err := certs.ParseVersion("1.3")
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), v)
+
+	_, err = certs.ParseVersion("1.0")
+	require.ErrorIs(t, err, certs.ErrUnknownVersion)
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	ids, err := certs.ParseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"})
+	require.NoError(t, err)
+	require.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, ids)
+
+	_, err = certs.ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})
+	require.ErrorIs(t, err, certs.ErrUnknownCipher)
+}
@@ -0,0 +1,8 @@
+// Package accesslog - форматы access log. Отдельно от middleware/logger, чтобы их мог проверять конфиг
+package accesslog
+
+const (
+	FormatSlog     = "slog"     // запись в slog, как остальные логи
+	FormatCombined = "combined" // Apache Combined Log Format
+	FormatECS      = "ecs"      // JSON по Elastic Common Schema
+)
@@ -0,0 +1,150 @@
+package sloglevel
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ComponentKey - атрибут, по которому различаются компоненты: log.With(slog.String("component", "middleware/logger"))
+const ComponentKey = "component"
+
+// Levels - уровень логов сервиса и отдельных компонентов. Временные уровни (Set) через ttl
+// сами возвращаются к base - уровню из конфига
+type Levels struct {
+	base slog.Leveler
+
+	mu        sync.RWMutex
+	overrides map[string]override // "" - весь сервис
+}
+
+type override struct {
+	level slog.Level
+	until time.Time
+}
+
+// Override - временный уровень. Component пустой - уровень всего сервиса
+type Override struct {
+	Component string     `json:"component,omitempty"`
+	Level     slog.Level `json:"level"`
+	Until     time.Time  `json:"until"`
+}
+
+func NewLevels(base slog.Leveler) *Levels {
+	return &Levels{
+		base:      base,
+		overrides: make(map[string]override),
+	}
+}
+
+// Level - действующий уровень компонента: его временный уровень, временный уровень сервиса или base
+func (l *Levels) Level(component string) slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if len(l.overrides) == 0 {
+		return l.base.Level()
+	}
+
+	now := time.Now()
+	if o, ok := l.overrides[component]; ok && now.Before(o.until) {
+		return o.level
+	}
+	if o, ok := l.overrides[""]; ok && now.Before(o.until) {
+		return o.level
+	}
+
+	return l.base.Level()
+}
+
+// Set меняет уровень компонента (component == "" - всего сервиса) на ttl
+func (l *Levels) Set(component string, level slog.Level, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune()
+	l.overrides[component] = override{level: level, until: time.Now().Add(ttl)}
+}
+
+// Reset возвращает компоненту уровень из конфига раньше времени
+func (l *Levels) Reset(component string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.overrides, component)
+}
+
+// Base - уровень из конфига
+func (l *Levels) Base() slog.Level {
+	return l.base.Level()
+}
+
+// Overrides - действующие временные уровни
+func (l *Levels) Overrides() []Override {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune()
+
+	result := make([]Override, 0, len(l.overrides))
+	for component, o := range l.overrides {
+		result = append(result, Override{Component: component, Level: o.level, Until: o.until})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Component < result[j].Component
+	})
+
+	return result
+}
+
+// удаляем истекшие, чтобы Level снова шел по быстрому пути
+func (l *Levels) prune() {
+	now := time.Now()
+	for component, o := range l.overrides {
+		if !now.Before(o.until) {
+			delete(l.overrides, component)
+		}
+	}
+}
+
+// Handler отбрасывает записи ниже уровня своего компонента.
+// Обернутый handler должен пропускать все уровни, которые можно выставить через Levels
+type Handler struct {
+	next      slog.Handler
+	levels    *Levels
+	component string
+	grouped   bool // после WithGroup атрибуты уже не верхнего уровня
+}
+
+func New(next slog.Handler, levels *Levels) *Handler {
+	return &Handler{next: next, levels: levels}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.levels.Level(h.component) && h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	component := h.component
+	for _, a := range attrs {
+		if a.Key == ComponentKey && !h.grouped {
+			component = a.Value.String()
+		}
+	}
+
+	return &Handler{next: h.next.WithAttrs(attrs), levels: h.levels, component: component, grouped: h.grouped}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &Handler{next: h.next.WithGroup(name), levels: h.levels, component: h.component, grouped: true}
+}
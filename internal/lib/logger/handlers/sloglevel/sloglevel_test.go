@@ -0,0 +1,89 @@
+package sloglevel_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/sloglevel"
+)
+
+func newLogger(t *testing.T, levels *sloglevel.Levels) (*slog.Logger, *bytes.Buffer) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	// внутренний handler пропускает все, решает sloglevel
+	inner := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+
+	return slog.New(sloglevel.New(inner, levels)), &buf
+}
+
+func lines(buf *bytes.Buffer) int {
+	return strings.Count(buf.String(), "\n")
+}
+
+func TestHandler(t *testing.T) {
+	base := new(slog.LevelVar)
+	base.Set(slog.LevelInfo)
+	levels := sloglevel.NewLevels(base)
+
+	log, buf := newLogger(t, levels)
+	mwLog := log.With(slog.String(sloglevel.ComponentKey, "middleware/logger"))
+	otherLog := log.With(slog.String(sloglevel.ComponentKey, "trash"))
+
+	mwLog.Debug("hidden")
+	require.Equal(t, 0, lines(buf))
+
+	// debug только для одного компонента
+	levels.Set("middleware/logger", slog.LevelDebug, time.Minute)
+	mwLog.Debug("shown")
+	mwLog.With(slog.String("op", "x")).Debug("shown, derived logger")
+	otherLog.Debug("hidden")
+	log.Debug("hidden")
+	require.Equal(t, 2, lines(buf))
+
+	// component внутри группы не переключает компонент
+	mwLog.WithGroup("request").With(slog.String(sloglevel.ComponentKey, "trash")).Debug("shown")
+	require.Equal(t, 3, lines(buf))
+
+	// уровень сервиса не перекрывает уровень компонента
+	levels.Set("", slog.LevelError, time.Minute)
+	otherLog.Warn("hidden")
+	mwLog.Debug("shown")
+	require.Equal(t, 4, lines(buf))
+
+	levels.Reset("")
+	levels.Reset("middleware/logger")
+	mwLog.Debug("hidden")
+	otherLog.Info("shown")
+	require.Equal(t, 5, lines(buf))
+
+	// уровень из конфига меняется на лету (перечитывание конфига)
+	base.Set(slog.LevelDebug)
+	otherLog.Debug("shown")
+	require.Equal(t, 6, lines(buf))
+}
+
+func TestLevels_Expire(t *testing.T) {
+	base := new(slog.LevelVar)
+	base.Set(slog.LevelInfo)
+	levels := sloglevel.NewLevels(base)
+
+	levels.Set("trash", slog.LevelDebug, 20*time.Millisecond)
+	levels.Set("", slog.LevelWarn, time.Hour)
+	require.Equal(t, slog.LevelDebug, levels.Level("trash"))
+	require.Len(t, levels.Overrides(), 2)
+
+	require.Eventually(t, func() bool {
+		return levels.Level("trash") == slog.LevelWarn
+	}, time.Second, 5*time.Millisecond)
+
+	overrides := levels.Overrides()
+	require.Len(t, overrides, 1)
+	require.Equal(t, "", overrides[0].Component)
+	require.Equal(t, slog.LevelWarn, overrides[0].Level)
+}
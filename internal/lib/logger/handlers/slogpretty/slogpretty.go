@@ -1,98 +1,236 @@
 package slogpretty
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"io"
-	stdLog "log"
 	"log/slog"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 )
 
+const timeFormat = "[15:04:05.000]"
+
 type PrettyHandlerOptions struct {
 	SlogOpts *slog.HandlerOptions
+
+	// NoColor - вывод без цветов, например в файл.
+	// Для stdout цвета и так выключаются, если это не терминал или задан NO_COLOR (см. color.NoColor)
+	NoColor bool
 }
 
 type PrettyHandler struct {
 	opts PrettyHandlerOptions
-	slog.Handler
-	l     *stdLog.Logger
+	goas []groupOrAttrs // группы и атрибуты из WithGroup/WithAttrs в порядке вызова
+
+	mu  *sync.Mutex // общий для всех производных хендлеров, чтобы записи не перемешивались
+	out io.Writer
+}
+
+// groupOrAttrs - либо открытая группа, либо атрибуты
+type groupOrAttrs struct {
+	group string
 	attrs []slog.Attr
 }
 
+// group - вложенная группа в выводе. Отдельный тип, чтобы отличать от map из значений атрибутов
+type group map[string]any
+
 func (opts PrettyHandlerOptions) NewPrettyHandler(
 	out io.Writer,
 ) *PrettyHandler {
 	h := &PrettyHandler{
-		Handler: slog.NewJSONHandler(out, opts.SlogOpts),
-		l:       stdLog.New(out, "", 0),
+		opts: opts,
+		mu:   &sync.Mutex{},
+		out:  out,
 	}
 
 	return h
 }
 
+func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.SlogOpts != nil && h.opts.SlogOpts.Level != nil {
+		minLevel = h.opts.SlogOpts.Level.Level()
+	}
+
+	return level >= minLevel
+}
+
 func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
-	level := r.Level.String() + ":"
+	fields := group{}
 
-	switch r.Level {
-	case slog.LevelDebug:
-		level = color.MagentaString(level)
-	case slog.LevelInfo:
-		level = color.BlueString(level)
-	case slog.LevelWarn:
-		level = color.YellowString(level)
-	case slog.LevelError:
-		level = color.RedString(level)
-	}
+	// атрибуты из WithAttrs попадают в группы, открытые до них, атрибуты записи - во все группы
+	cur := fields
+	for _, goa := range h.goas {
+		if goa.group != "" {
+			g := group{}
+			cur[goa.group] = g
+			cur = g
 
-	fields := make(map[string]interface{}, r.NumAttrs())
+			continue
+		}
+
+		for _, a := range goa.attrs {
+			addAttr(cur, a)
+		}
+	}
 
 	r.Attrs(func(a slog.Attr) bool {
-		fields[a.Key] = a.Value.Any()
+		addAttr(cur, a)
 
 		return true
 	})
 
-	for _, a := range h.attrs {
-		fields[a.Key] = a.Value.Any()
+	prune(fields)
+
+	buf := &bytes.Buffer{}
+
+	if !r.Time.IsZero() {
+		buf.WriteString(r.Time.Format(timeFormat))
+		buf.WriteByte(' ')
 	}
 
-	var b []byte
-	var err error
+	buf.WriteString(h.levelColor(r.Level).Sprint(r.Level.String() + ":"))
+	buf.WriteByte(' ')
+	buf.WriteString(h.color(color.FgCyan).Sprint(r.Message))
 
 	if len(fields) > 0 {
-		b, err = json.MarshalIndent(fields, "", "  ")
-		if err != nil {
+		b := &bytes.Buffer{}
+		enc := json.NewEncoder(b)
+		enc.SetIndent("", "  ")
+		// url читаются как есть, без \u0026 вместо &
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(fields); err != nil {
 			return err
 		}
+
+		buf.WriteByte(' ')
+		buf.WriteString(h.color(color.FgWhite).Sprint(strings.TrimSuffix(b.String(), "\n")))
 	}
 
-	timeStr := r.Time.Format("[15:05:05.000]")
-	msg := color.CyanString(r.Message)
+	buf.WriteByte('\n')
 
-	h.l.Println(
-		timeStr,
-		level,
-		msg,
-		color.WhiteString(string(b)),
-	)
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	return nil
+	_, err := h.out.Write(buf.Bytes())
+
+	return err
 }
 
 func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &PrettyHandler{
-		Handler: h.Handler,
-		l:       h.l,
-		attrs:   attrs,
+	if len(attrs) == 0 {
+		return h
 	}
+
+	return h.with(groupOrAttrs{attrs: attrs})
 }
 
 func (h *PrettyHandler) WithGroup(name string) slog.Handler {
-	// TODO: implement
-	return &PrettyHandler{
-		Handler: h.Handler.WithGroup(name),
-		l:       h.l,
+	if name == "" {
+		return h
+	}
+
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *PrettyHandler) with(goa groupOrAttrs) *PrettyHandler {
+	h2 := *h
+	h2.goas = make([]groupOrAttrs, len(h.goas)+1)
+	copy(h2.goas, h.goas)
+	h2.goas[len(h.goas)] = goa
+
+	return &h2
+}
+
+func (h *PrettyHandler) levelColor(level slog.Level) *color.Color {
+	switch {
+	case level >= slog.LevelError:
+		return h.color(color.FgRed)
+	case level >= slog.LevelWarn:
+		return h.color(color.FgYellow)
+	case level >= slog.LevelInfo:
+		return h.color(color.FgBlue)
+	default:
+		return h.color(color.FgMagenta)
+	}
+}
+
+func (h *PrettyHandler) color(attr color.Attribute) *color.Color {
+	c := color.New(attr)
+	if h.opts.NoColor {
+		c.DisableColor()
+	}
+
+	return c
+}
+
+func addAttr(g group, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+
+	// пустой атрибут не выводим
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() != slog.KindGroup {
+		g[a.Key] = value(a.Value)
+		return
+	}
+
+	attrs := a.Value.Group()
+	if len(attrs) == 0 {
+		return
+	}
+
+	// группа без имени встраивается в текущую
+	sub := g
+	if a.Key != "" {
+		var ok bool
+		if sub, ok = g[a.Key].(group); !ok {
+			sub = group{}
+			g[a.Key] = sub
+		}
+	}
+
+	for _, ga := range attrs {
+		addAttr(sub, ga)
+	}
+}
+
+func value(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindDuration:
+		// 1.5s читается лучше, чем наносекунды
+		return v.Duration().String()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		// error без этого превратится в {}
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+	}
+
+	return v.Any()
+}
+
+// prune убирает группы, в которые так ничего и не попало
+func prune(g group) {
+	for k, v := range g {
+		sub, ok := v.(group)
+		if !ok {
+			continue
+		}
+
+		prune(sub)
+		if len(sub) == 0 {
+			delete(g, k)
+		}
 	}
 }
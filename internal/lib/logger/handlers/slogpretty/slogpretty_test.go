@@ -0,0 +1,135 @@
+package slogpretty_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogpretty"
+)
+
+// records - каждая запись пишется одним Write
+type records struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *records) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines = append(r.lines, string(p))
+
+	return len(p), nil
+}
+
+func newHandler(level slog.Level) (*slogpretty.PrettyHandler, *records) {
+	out := &records{}
+	opts := slogpretty.PrettyHandlerOptions{
+		SlogOpts: &slog.HandlerOptions{Level: level},
+		NoColor:  true,
+	}
+
+	return opts.NewPrettyHandler(out), out
+}
+
+// parse разбирает строку "[15:04:05.000] INFO: msg {json}" обратно в map
+func parse(t *testing.T, line string) map[string]any {
+	t.Helper()
+
+	line = strings.TrimSuffix(line, "\n")
+	m := map[string]any{}
+
+	if strings.HasPrefix(line, "[") {
+		ts, rest, ok := strings.Cut(line, "] ")
+		require.True(t, ok, line)
+		m[slog.TimeKey] = strings.TrimPrefix(ts, "[")
+		line = rest
+	}
+
+	level, rest, ok := strings.Cut(line, ": ")
+	require.True(t, ok, line)
+	m[slog.LevelKey] = level
+
+	msg, fields, _ := strings.Cut(rest, " {")
+	m[slog.MessageKey] = msg
+
+	if fields != "" {
+		require.NoError(t, json.Unmarshal([]byte("{"+fields), &m), line)
+	}
+
+	return m
+}
+
+func TestSlogtest(t *testing.T) {
+	h, out := newHandler(slog.LevelInfo)
+
+	results := func() []map[string]any {
+		ms := make([]map[string]any, 0, len(out.lines))
+		for _, line := range out.lines {
+			ms = append(ms, parse(t, line))
+		}
+
+		return ms
+	}
+
+	require.NoError(t, slogtest.TestHandler(h, results))
+}
+
+func TestPrettyHandler(t *testing.T) {
+	h, out := newHandler(slog.LevelDebug)
+	log := slog.New(h)
+
+	log.With(slog.String("a", "1")).
+		With(slog.String("b", "2")).
+		WithGroup("req").
+		With(slog.String("id", "42")).
+		WithGroup("user").
+		Info("hello", slog.String("name", "bob"), slog.Duration("took", 1500*time.Millisecond))
+
+	require.Len(t, out.lines, 1)
+
+	m := parse(t, out.lines[0])
+	assert.Equal(t, "INFO", m[slog.LevelKey])
+	assert.Equal(t, "hello", m[slog.MessageKey])
+	// атрибуты копятся, а не заменяются
+	assert.Equal(t, "1", m["a"])
+	assert.Equal(t, "2", m["b"])
+	assert.Equal(t, map[string]any{
+		"id":   "42",
+		"user": map[string]any{"name": "bob", "took": "1.5s"},
+	}, m["req"])
+}
+
+func TestPrettyHandler_Format(t *testing.T) {
+	h, out := newHandler(slog.LevelDebug)
+
+	r := slog.NewRecord(time.Date(2024, 1, 2, 13, 4, 5, 6e6, time.UTC), slog.LevelWarn, "disk is full", 0)
+	r.AddAttrs(slog.Any("error", errors.New("no space left")))
+	require.NoError(t, h.Handle(context.Background(), r))
+
+	require.Len(t, out.lines, 1)
+	// без цветов: никаких escape-последовательностей
+	assert.Equal(t, "[13:04:05.006] WARN: disk is full {\n  \"error\": \"no space left\"\n}\n", out.lines[0])
+}
+
+func TestPrettyHandler_Enabled(t *testing.T) {
+	h, out := newHandler(slog.LevelInfo)
+	log := slog.New(h)
+
+	log.Debug("hidden")
+	log.Info("shown")
+
+	require.Len(t, out.lines, 1)
+	assert.Equal(t, "[", out.lines[0][:1])
+	assert.Contains(t, out.lines[0], "INFO: shown\n")
+}
@@ -0,0 +1,94 @@
+package slogsample
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type Options struct {
+	First      int           // сколько записей с одним сообщением пропускаем за tick. <= 0 - без сэмплирования
+	Thereafter int           // дальше пропускаем каждую Thereafter-ю. <= 0 - остальные отбрасываем
+	Tick       time.Duration // окно подсчета, по умолчанию секунда
+}
+
+// Handler сэмплирует частые записи: первые First за tick, потом 1 из Thereafter.
+// Считаем по уровню и сообщению. Ошибки пропускаются всегда
+type Handler struct {
+	next    slog.Handler
+	sampler *sampler // общий для всех производных handler'ов
+}
+
+type key struct {
+	level slog.Level
+	msg   string
+}
+
+type sampler struct {
+	opts Options
+	now  func() time.Time
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[key]int
+}
+
+func New(next slog.Handler, opts Options) *Handler {
+	if opts.Tick <= 0 {
+		opts.Tick = time.Second
+	}
+
+	return &Handler{
+		next: next,
+		sampler: &sampler{
+			opts:   opts,
+			now:    time.Now,
+			counts: make(map[key]int),
+		},
+	}
+}
+
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelError && !h.sampler.allow(key{level: r.Level, msg: r.Message}) {
+		return nil
+	}
+
+	return h.next.Handle(ctx, r)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{next: h.next.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name), sampler: h.sampler}
+}
+
+func (s *sampler) allow(k key) bool {
+	if s.opts.First <= 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// новое окно - счетчики с нуля. Заодно map не растет из-за редких сообщений
+	if now := s.now(); now.Sub(s.windowStart) >= s.opts.Tick {
+		s.windowStart = now
+		clear(s.counts)
+	}
+
+	s.counts[k]++
+	n := s.counts[k]
+
+	if n <= s.opts.First {
+		return true
+	}
+
+	return s.opts.Thereafter > 0 && (n-s.opts.First)%s.opts.Thereafter == 0
+}
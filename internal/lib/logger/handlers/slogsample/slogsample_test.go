@@ -0,0 +1,77 @@
+package slogsample
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newLogger(opts Options) (*slog.Logger, *Handler, *bytes.Buffer) {
+	var buf bytes.Buffer
+	h := New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), opts)
+
+	return slog.New(h), h, &buf
+}
+
+func count(buf *bytes.Buffer, msg string) int {
+	return strings.Count(buf.String(), "msg="+msg)
+}
+
+func TestHandler(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	log, h, buf := newLogger(Options{First: 3, Thereafter: 5})
+	h.sampler.now = func() time.Time { return now }
+
+	// сэмплер общий и для логгеров с атрибутами
+	reqLog := log.With(slog.String("component", "middleware/logger"))
+	for i := 0; i < 23; i++ {
+		reqLog.Info("request", slog.Int("i", i))
+	}
+	// первые 3, потом 8-я, 13-я, 18-я и 23-я
+	assert.Equal(t, 7, count(buf, "request"))
+	assert.Contains(t, buf.String(), "i=7\n")
+	assert.NotContains(t, buf.String(), "i=8\n")
+
+	// другое сообщение считается отдельно
+	log.Info("other")
+	assert.Equal(t, 1, count(buf, "other"))
+
+	// ошибки не сэмплируются
+	for i := 0; i < 10; i++ {
+		log.Error("failed")
+	}
+	assert.Equal(t, 10, count(buf, "failed"))
+
+	// в новом окне снова первые 3
+	now = now.Add(time.Second)
+	buf.Reset()
+	for i := 0; i < 4; i++ {
+		reqLog.Info("request")
+	}
+	assert.Equal(t, 3, count(buf, "request"))
+}
+
+func TestHandler_DropThereafter(t *testing.T) {
+	log, _, buf := newLogger(Options{First: 2})
+
+	for i := 0; i < 10; i++ {
+		log.Info("request")
+	}
+
+	assert.Equal(t, 2, count(buf, "request"))
+}
+
+func TestHandler_Disabled(t *testing.T) {
+	log, _, buf := newLogger(Options{})
+
+	for i := 0; i < 10; i++ {
+		log.Debug("request")
+	}
+
+	assert.Equal(t, 10, count(buf, "request"))
+}
@@ -0,0 +1,41 @@
+package slogtrace_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+
+	"url-shortener/internal/lib/logger/handlers/slogtrace"
+)
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slogtrace.New(slog.NewJSONHandler(&buf, nil))).With(slog.String("op", "test"))
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	log.InfoContext(ctx, "with span")
+
+	var rec map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", rec["trace_id"])
+	require.Equal(t, "00f067aa0ba902b7", rec["span_id"])
+	require.Equal(t, "test", rec["op"])
+
+	buf.Reset()
+	log.Info("without span")
+
+	rec = nil
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	require.NotContains(t, rec, "trace_id")
+}
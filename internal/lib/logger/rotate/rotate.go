@@ -0,0 +1,287 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// формат времени в имени старого файла: app-20240102T150405.000.log
+const backupTimeFormat = "20060102T150405.000"
+
+type Options struct {
+	Path       string        // текущий файл, старые лежат рядом
+	MaxSize    int64         // ротация при превышении размера в байтах, 0 - без ограничения
+	Interval   time.Duration // ротация на границе интервала (24h - в полночь UTC), 0 - только по размеру
+	MaxBackups int           // сколько старых файлов хранить, 0 - все
+	Compress   bool          // сжимать старые файлы в .gz
+}
+
+// Writer - io.Writer в файл с ротацией по размеру и времени.
+// Сжатие и удаление старых файлов идут в фоне и не задерживают запись
+type Writer struct {
+	opts Options
+	now  func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File // nil, если не удалось открыть после ротации - пробуем снова при записи
+	closed   bool
+	size     int64
+	openedAt time.Time
+
+	millMu sync.Mutex // сжатие и удаление старых файлов по одному
+	wg     sync.WaitGroup
+}
+
+func New(opts Options) (*Writer, error) {
+	const op = "rotate.New"
+
+	w := &Writer{opts: opts, now: time.Now}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := w.open(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return w, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	const op = "rotate.Write"
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, fmt.Errorf("%s: %w", op, os.ErrClosed)
+	}
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	if w.shouldRotate(len(p)) {
+		if err := w.rotate(); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// Rotate - ротация прямо сейчас
+func (w *Writer) Rotate() error {
+	const op = "rotate.Rotate"
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed || w.file == nil {
+		return fmt.Errorf("%s: %w", op, os.ErrClosed)
+	}
+
+	if err := w.rotate(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Close закрывает файл и ждет фонового сжатия
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	var err error
+	w.closed = true
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+
+	return err
+}
+
+func (w *Writer) shouldRotate(n int) bool {
+	// запись больше max_size целиком все равно пишем в пустой файл
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(n) > w.opts.MaxSize {
+		return true
+	}
+
+	now := w.now()
+	if w.opts.Interval > 0 && !now.Truncate(w.opts.Interval).Equal(w.openedAt.Truncate(w.opts.Interval)) {
+		if w.size == 0 {
+			// пустой файл не ротируем, просто начинаем новый интервал
+			w.openedAt = now
+			return false
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	w.file = f
+	w.size = info.Size()
+	// дописываем в старый файл - считаем от его изменения, чтобы не пропустить границу интервала
+	w.openedAt = w.now()
+	if info.Size() > 0 {
+		w.openedAt = info.ModTime()
+	}
+
+	return nil
+}
+
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	backup := w.backupName(w.now())
+	renameErr := os.Rename(w.opts.Path, backup)
+
+	// открываем в любом случае, чтобы не терять следующие записи
+	if err := w.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.mill(backup)
+	}()
+
+	return nil
+}
+
+func (w *Writer) backupName(t time.Time) string {
+	ext := filepath.Ext(w.opts.Path)
+	prefix := strings.TrimSuffix(w.opts.Path, ext) + "-" + t.UTC().Format(backupTimeFormat)
+
+	name := prefix + ext
+	for i := 1; exists(name) || exists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s.%d%s", prefix, i, ext)
+	}
+
+	return name
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
+
+// mill сжимает новый старый файл и удаляет лишние. Ошибки писать некуда - это и есть лог
+func (w *Writer) mill(backup string) {
+	w.millMu.Lock()
+	defer w.millMu.Unlock()
+
+	if w.opts.Compress {
+		if err := compress(backup); err == nil {
+			_ = os.Remove(backup)
+		}
+	}
+
+	if w.opts.MaxBackups <= 0 {
+		return
+	}
+
+	backups := w.backups()
+	for len(backups) > w.opts.MaxBackups {
+		_ = os.Remove(backups[0])
+		backups = backups[1:]
+	}
+}
+
+// backups - старые файлы от самого старого к новому
+func (w *Writer) backups() []string {
+	ext := filepath.Ext(w.opts.Path)
+	prefix := strings.TrimSuffix(w.opts.Path, ext) + "-"
+
+	matches, _ := filepath.Glob(prefix + "*")
+
+	var backups []string
+	for _, m := range matches {
+		name := strings.TrimSuffix(m, ".gz")
+		if !strings.HasSuffix(name, ext) {
+			continue
+		}
+
+		// только наши файлы: после префикса - время ротации (и номер, если время совпало)
+		stamp := strings.TrimSuffix(name, ext)[len(prefix):]
+		if len(stamp) < len(backupTimeFormat) {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, stamp[:len(backupTimeFormat)]); err != nil {
+			continue
+		}
+
+		backups = append(backups, m)
+	}
+
+	// время в имени сортируется как строка
+	sort.Strings(backups)
+
+	return backups
+}
+
+func compress(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(dst.Name())
+		return err
+	}
+
+	if err := gz.Close(); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(dst.Name())
+		return err
+	}
+
+	return dst.Close()
+}
@@ -0,0 +1,113 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newWriter(t *testing.T, opts Options, now *time.Time) *Writer {
+	t.Helper()
+
+	w, err := New(opts)
+	require.NoError(t, err)
+	w.now = func() time.Time { return *now }
+	t.Cleanup(func() { _ = w.Close() })
+
+	return w
+}
+
+func write(t *testing.T, w *Writer, s string) {
+	t.Helper()
+
+	_, err := w.Write([]byte(s))
+	require.NoError(t, err)
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		r = gz
+	}
+
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(b)
+}
+
+func TestWriter_Size(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	w := newWriter(t, Options{Path: path, MaxSize: 10, MaxBackups: 2, Compress: true}, &now)
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		write(t, w, line)
+		now = now.Add(time.Second)
+	}
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "fourth\n", read(t, path))
+
+	// first ушел в самый старый файл и удален: храним только 2
+	backups := w.backups()
+	require.Equal(t, []string{
+		filepath.Join(dir, "app-20240102T150407.000.log.gz"),
+		filepath.Join(dir, "app-20240102T150408.000.log.gz"),
+	}, backups)
+	assert.Equal(t, "second\n", read(t, backups[0]))
+	assert.Equal(t, "third\n", read(t, backups[1]))
+}
+
+func TestWriter_Interval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	now := time.Date(2024, 1, 2, 23, 59, 0, 0, time.UTC)
+
+	w := newWriter(t, Options{Path: path, Interval: 24 * time.Hour}, &now)
+
+	write(t, w, "day 1\n")
+	now = now.Add(30 * time.Second)
+	write(t, w, "day 1 again\n")
+	// полночь - новый файл
+	now = now.Add(time.Minute)
+	write(t, w, "day 2\n")
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "day 2\n", read(t, path))
+
+	backups := w.backups()
+	require.Len(t, backups, 1)
+	assert.Equal(t, "day 1\nday 1 again\n", read(t, backups[0]))
+}
+
+func TestWriter_Append(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("old\n"), 0o644))
+	now := time.Now()
+
+	w := newWriter(t, Options{Path: path, MaxSize: 100}, &now)
+	write(t, w, "new\n")
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "old\nnew\n", read(t, path))
+
+	_, err := w.Write([]byte("closed\n"))
+	require.ErrorIs(t, err, os.ErrClosed)
+}
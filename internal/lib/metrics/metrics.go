@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "url_shortener"
+
+// результаты редиректа
+const (
+	RedirectHit     = "hit"
+	RedirectMiss    = "miss"
+	RedirectExpired = "expired" // ссылка в корзине
+	RedirectError   = "error"
+)
+
+// метрики регистрируются в prometheus.DefaultRegisterer, там же метрики Go runtime и процесса
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route pattern, method and status.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route pattern and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	Redirects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redirects_total",
+		Help:      "Short link redirects by result: hit, miss, expired, error.",
+	}, []string{"result"})
+
+	StorageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Storage operation latency.",
+		// sqlite на локальном диске: в основном доли миллисекунды
+		Buckets: []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+	}, []string{"operation"})
+
+	SSOCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sso_calls_total",
+		Help:      "SSO gRPC calls by method and status code.",
+	}, []string{"method", "code"})
+)
+
+// ObserveStorage - вызывать через defer в начале операции: defer metrics.ObserveStorage(op, time.Now())
+func ObserveStorage(operation string, start time.Time) {
+	StorageDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
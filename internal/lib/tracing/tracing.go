@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// куда отправляем span'ы
+const (
+	ExporterNone   = "none"   // span'ы не пишутся, но traceparent пробрасывается дальше
+	ExporterOTLP   = "otlp"   // OTLP по gRPC: collector, Jaeger, Tempo
+	ExporterStdout = "stdout" // JSON в stdout или файл, для отладки без collector'а
+)
+
+type Options struct {
+	ServiceName string
+	Exporter    string
+	Endpoint    string // otlp: host:port
+	Insecure    bool   // otlp: без TLS
+	FilePath    string // stdout: пусто - в stdout
+	SampleRatio float64
+}
+
+// Setup настраивает глобальные TracerProvider и W3C propagator (traceparent, baggage).
+// shutdown отправляет оставшиеся span'ы, вызывать при остановке
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	const op = "tracing.Setup"
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	noop := func(context.Context) error { return nil }
+
+	var (
+		exporter sdktrace.SpanExporter
+		closer   io.Closer
+	)
+
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return noop, nil
+	case ExporterOTLP:
+		clientOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+
+		exporter, err = otlptracegrpc.New(ctx, clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	case ExporterStdout:
+		var out io.Writer = os.Stdout
+		if opts.FilePath != "" {
+			f, err := os.OpenFile(opts.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+			out, closer = f, f
+		}
+
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(out))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unknown exporter %q", op, opts.Exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		// если вызывающий сервис уже решил, писать ли trace, следуем его решению
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(opts.ServiceName),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			err = errors.Join(err, closer.Close())
+		}
+
+		return err
+	}, nil
+}
@@ -0,0 +1,39 @@
+package tracing_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+
+	"url-shortener/internal/lib/tracing"
+)
+
+func TestSetup_StdoutFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName: "test",
+		Exporter:    tracing.ExporterStdout,
+		FilePath:    path,
+		SampleRatio: 1,
+	})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "test-span")
+	span.End()
+
+	require.NoError(t, shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"Name":"test-span"`)
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := tracing.Setup(context.Background(), tracing.Options{Exporter: "zipkin"})
+	require.Error(t, err)
+}
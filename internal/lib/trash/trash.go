@@ -0,0 +1,49 @@
+package trash
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+type Purger interface {
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RunPurge раз в interval окончательно удаляет ссылки, пролежавшие в корзине дольше retention.
+// Работает до отмены ctx
+func RunPurge(ctx context.Context, log *slog.Logger, purger Purger, retention time.Duration, interval time.Duration) {
+	log = log.With(slog.String("component", "trash"))
+
+	if retention <= 0 || interval <= 0 {
+		log.Info("trash purge disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purge(ctx, log, purger, retention)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func purge(ctx context.Context, log *slog.Logger, purger Purger, retention time.Duration) {
+	purged, err := purger.PurgeDeleted(ctx, time.Now().Add(-retention))
+	if err != nil {
+		log.Error("failed to purge deleted urls", sl.Err(err))
+		return
+	}
+
+	if purged > 0 {
+		log.Info("deleted urls purged", slog.Int64("count", purged))
+	}
+}
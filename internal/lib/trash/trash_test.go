@@ -0,0 +1,69 @@
+package trash_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/trash"
+)
+
+type fakePurger struct {
+	mu    sync.Mutex
+	calls []time.Time
+}
+
+func (p *fakePurger) PurgeDeleted(_ context.Context, before time.Time) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.calls = append(p.calls, before)
+
+	return 1, nil
+}
+
+func (p *fakePurger) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.calls)
+}
+
+func TestRunPurge(t *testing.T) {
+	purger := &fakePurger{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		trash.RunPurge(ctx, slogdiscard.NewDiscardLogger(), purger, time.Hour, 10*time.Millisecond)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return purger.count() >= 2 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunPurge did not stop after context cancel")
+	}
+
+	purger.mu.Lock()
+	defer purger.mu.Unlock()
+	assert.WithinDuration(t, time.Now().Add(-time.Hour), purger.calls[0], time.Second)
+}
+
+func TestRunPurge_Disabled(t *testing.T) {
+	purger := &fakePurger{}
+
+	// при выключенной очистке сразу выходим
+	trash.RunPurge(context.Background(), slogdiscard.NewDiscardLogger(), purger, 0, time.Minute)
+
+	assert.Zero(t, purger.count())
+}
@@ -0,0 +1,146 @@
+package urlchain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"url-shortener/internal/storage"
+)
+
+var (
+	ErrShortLink    = errors.New("url points to another short link")
+	ErrRedirectLoop = errors.New("url creates a redirect loop")
+	ErrChainTooDeep = errors.New("short link chain is too deep")
+	ErrBrokenChain  = errors.New("url points to a missing short link")
+	ErrServicePath  = errors.New("url points to the service, not to a short link")
+)
+
+// что делать со ссылкой на нашу же короткую ссылку
+const (
+	ModeAllow   = "allow"   // сохраняем как есть, если нет цикла и цепочка не слишком длинная
+	ModeFlatten = "flatten" // сохраняем конечный url цепочки
+	ModeReject  = "reject"  // не даем сохранить
+)
+
+type URLGetter interface {
+	GetURL(ctx context.Context, alias string) (string, error)
+}
+
+// Resolver проходит по цепочке коротких ссылок на наших публичных хостах
+type Resolver struct {
+	urlGetter URLGetter
+	hosts     map[string]struct{}
+	mode      string
+	maxDepth  int
+}
+
+// New
+// publicHosts - хосты, на которых доступны наши короткие ссылки (порт игнорируется)
+func New(urlGetter URLGetter, publicHosts []string, mode string, maxDepth int) (*Resolver, error) {
+	const op = "urlchain.New"
+
+	switch mode {
+	case ModeAllow, ModeFlatten, ModeReject:
+	default:
+		return nil, fmt.Errorf("%s: unknown mode %q", op, mode)
+	}
+
+	r := &Resolver{
+		urlGetter: urlGetter,
+		hosts:     make(map[string]struct{}, len(publicHosts)),
+		mode:      mode,
+		maxDepth:  maxDepth,
+	}
+
+	for _, h := range publicHosts {
+		r.hosts[hostname(h)] = struct{}{}
+	}
+
+	return r, nil
+}
+
+// Resolve возвращает url, который нужно сохранить для alias
+func (r *Resolver) Resolve(ctx context.Context, rawURL string, alias string) (string, error) {
+	const op = "urlchain.Resolve"
+
+	next, own := r.alias(rawURL)
+	if !own {
+		return rawURL, nil
+	}
+
+	// на наших хостах можно ссылаться только на короткие ссылки: /, /url/..., /admin/... - это сам сервис
+	if next == "" {
+		return "", ErrServicePath
+	}
+
+	if r.mode == ModeReject {
+		return "", ErrShortLink
+	}
+
+	visited := map[string]struct{}{alias: {}}
+	current := rawURL
+
+	for depth := 1; own; depth++ {
+		if _, seen := visited[next]; seen {
+			return "", ErrRedirectLoop
+		}
+		visited[next] = struct{}{}
+
+		if r.maxDepth > 0 && depth > r.maxDepth {
+			return "", ErrChainTooDeep
+		}
+
+		dest, err := r.urlGetter.GetURL(ctx, next)
+		if errors.Is(err, storage.ErrUrlNotFound) || errors.Is(err, storage.ErrUrlDeleted) {
+			return "", ErrBrokenChain
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+
+		current = dest
+		next, own = r.alias(dest)
+		if own && next == "" {
+			return "", ErrServicePath
+		}
+	}
+
+	if r.mode == ModeFlatten {
+		return current, nil
+	}
+
+	return rawURL, nil
+}
+
+// alias короткой ссылки, если url ведет на наш публичный хост.
+// own - url на нашем хосте, alias пустой, если путь не может быть короткой ссылкой
+func (r *Resolver) alias(rawURL string) (alias string, own bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+
+	if _, ok := r.hosts[hostname(u.Host)]; !ok {
+		return "", false
+	}
+
+	alias = strings.TrimPrefix(u.Path, "/")
+	// /url/..., /me/quota и тд - это не короткие ссылки
+	if strings.Contains(alias, "/") {
+		return "", true
+	}
+
+	return alias, true
+}
+
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,102 @@
+package urlchain_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/urlchain"
+	"url-shortener/internal/storage"
+)
+
+type fakeStorage map[string]string
+
+func (s fakeStorage) GetURL(_ context.Context, alias string) (string, error) {
+	u, ok := s[alias]
+	if !ok {
+		return "", storage.ErrUrlNotFound
+	}
+
+	return u, nil
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	urls := fakeStorage{
+		"google": "https://google.com",
+		"chain1": "https://short.io/google",
+		"chain2": "https://short.io/chain1",
+		"loop1":  "https://short.io/loop2",
+		"loop2":  "https://short.io/loop1",
+		"admin":  "https://short.io/admin/log-level", // сохранено до запрета ссылок на сервис
+	}
+
+	cases := []struct {
+		name     string
+		mode     string
+		url      string
+		alias    string
+		maxDepth int
+		want     string
+		wantErr  error
+	}{
+		{name: "External url", mode: urlchain.ModeFlatten, url: "https://google.com/abc", alias: "new", want: "https://google.com/abc"},
+		{name: "Service route", mode: urlchain.ModeFlatten, url: "https://short.io/me/quota", alias: "new", wantErr: urlchain.ErrServicePath},
+		{name: "Service route in reject mode", mode: urlchain.ModeReject, url: "https://short.io/url/abc/restore", alias: "new", wantErr: urlchain.ErrServicePath},
+		{name: "Root path", mode: urlchain.ModeAllow, url: "http://short.io:8080/", alias: "new", wantErr: urlchain.ErrServicePath},
+		{name: "Root without slash", mode: urlchain.ModeAllow, url: "http://short.io", alias: "new", wantErr: urlchain.ErrServicePath},
+		{name: "Single segment route", mode: urlchain.ModeFlatten, url: "https://short.io/healthz", alias: "new", wantErr: urlchain.ErrBrokenChain},
+		{name: "Chain to service route", mode: urlchain.ModeFlatten, url: "https://short.io/admin", alias: "new", wantErr: urlchain.ErrServicePath},
+		{name: "Flatten", mode: urlchain.ModeFlatten, url: "https://short.io/google", alias: "new", want: "https://google.com"},
+		{name: "Flatten with port and case", mode: urlchain.ModeFlatten, url: "http://SHORT.io:8080/chain2", alias: "new", want: "https://google.com"},
+		{name: "Allow", mode: urlchain.ModeAllow, url: "https://short.io/google", alias: "new", want: "https://short.io/google"},
+		{name: "Reject", mode: urlchain.ModeReject, url: "https://short.io/google", alias: "new", wantErr: urlchain.ErrShortLink},
+		{name: "Self reference", mode: urlchain.ModeAllow, url: "https://short.io/new", alias: "new", wantErr: urlchain.ErrRedirectLoop},
+		{name: "Loop", mode: urlchain.ModeFlatten, url: "https://short.io/loop1", alias: "new", wantErr: urlchain.ErrRedirectLoop},
+		{name: "Too deep", mode: urlchain.ModeFlatten, url: "https://short.io/chain2", alias: "new", maxDepth: 2, wantErr: urlchain.ErrChainTooDeep},
+		{name: "Missing alias", mode: urlchain.ModeFlatten, url: "https://short.io/missing", alias: "new", wantErr: urlchain.ErrBrokenChain},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			maxDepth := tc.maxDepth
+			if maxDepth == 0 {
+				maxDepth = 3
+			}
+
+			r, err := urlchain.New(urls, []string{"short.io"}, tc.mode, maxDepth)
+			require.NoError(t, err)
+
+			got, err := r.Resolve(context.Background(), tc.url, tc.alias)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestResolver_StorageError(t *testing.T) {
+	r, err := urlchain.New(errGetter{}, []string{"short.io"}, urlchain.ModeFlatten, 3)
+	require.NoError(t, err)
+
+	_, err = r.Resolve(context.Background(), "https://short.io/abc", "new")
+	require.Error(t, err)
+}
+
+func TestNew_UnknownMode(t *testing.T) {
+	_, err := urlchain.New(fakeStorage{}, nil, "follow", 3)
+	require.Error(t, err)
+}
+
+type errGetter struct{}
+
+func (errGetter) GetURL(context.Context, string) (string, error) {
+	return "", errors.New("unexpected error")
+}
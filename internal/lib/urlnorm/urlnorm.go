@@ -0,0 +1,65 @@
+package urlnorm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// порты по умолчанию, их в url можно не указывать
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// Normalize приводит url к одному виду, чтобы одинаковые адреса совпадали как строки:
+// схема и хост в нижнем регистре, без порта по умолчанию, пустой путь = "/", параметры запроса по алфавиту
+func Normalize(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if port == defaultPorts[u.Scheme] {
+		port = ""
+	}
+
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		// ipv6
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	if u.Path == "" && u.Host != "" {
+		u.Path = "/"
+	}
+
+	// Encode сортирует по ключу, порядок значений одного ключа сохраняется
+	if u.RawQuery != "" {
+		u.RawQuery = u.Query().Encode()
+	}
+
+	return u.String(), nil
+}
+
+// Hash - sha256 от нормализованного url, по нему ищем уже сокращенные ссылки
+func Hash(rawURL string) (string, error) {
+	normalized, err := Normalize(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,73 @@
+package urlnorm_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/urlnorm"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{
+			name: "Scheme and host case",
+			url:  "HTTPS://Google.COM/Search",
+			want: "https://google.com/Search",
+		},
+		{
+			name: "Default port",
+			url:  "https://google.com:443/",
+			want: "https://google.com/",
+		},
+		{
+			name: "Custom port",
+			url:  "http://google.com:8080/",
+			want: "http://google.com:8080/",
+		},
+		{
+			name: "Empty path",
+			url:  "https://google.com",
+			want: "https://google.com/",
+		},
+		{
+			name: "Sorted query",
+			url:  "https://google.com/search?q=go&a=2&a=1",
+			want: "https://google.com/search?a=2&a=1&q=go",
+		},
+		{
+			name: "IPv6",
+			url:  "http://[::1]:80/path",
+			want: "http://[::1]/path",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := urlnorm.Normalize(tc.url)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	h1, err := urlnorm.Hash("https://Google.com:443?b=1&a=2")
+	require.NoError(t, err)
+
+	h2, err := urlnorm.Hash("https://google.com/?a=2&b=1")
+	require.NoError(t, err)
+
+	require.Equal(t, h1, h2)
+
+	_, err = urlnorm.Hash("http://[::1")
+	require.Error(t, err)
+}
@@ -0,0 +1,290 @@
+package urlpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPrivateAddress = errors.New("url points to a private network address")
+	ErrServiceHost    = errors.New("url points to the service itself")
+	ErrUnresolvedHost = errors.New("failed to resolve url host")
+)
+
+// Resolver - net.DefaultResolver, в тестах подменяется
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NetworkGuard не дает делать короткие ссылки на внутреннюю сеть:
+// приватные, loopback, link-local, CGNAT и NAT64 адреса, а также хосты самого сервиса
+type NetworkGuard struct {
+	resolver       Resolver // nil - проверяем только ip, указанные прямо в url
+	resolveTimeout time.Duration
+	serviceHosts   map[string]struct{}
+	allowDomains   *domainList
+	allowNets      []*net.IPNet
+}
+
+// NewNetworkGuard
+// serviceHosts - хосты сервиса
+// allowlist - домены ("example.com", "*.example.com"), ip и подсети ("10.1.0.0/16"), на которые ссылки разрешены
+func NewNetworkGuard(
+	resolver Resolver,
+	resolveTimeout time.Duration,
+	serviceHosts []string,
+	allowlist []string,
+) (*NetworkGuard, error) {
+	const op = "urlpolicy.NewNetworkGuard"
+
+	g := &NetworkGuard{
+		resolver:       resolver,
+		resolveTimeout: resolveTimeout,
+		serviceHosts:   make(map[string]struct{}, len(serviceHosts)),
+		allowDomains:   &domainList{exact: make(map[string]struct{})},
+	}
+
+	for _, h := range serviceHosts {
+		if h = stripPort(h); h != "" {
+			g.serviceHosts[h] = struct{}{}
+		}
+	}
+
+	for _, entry := range allowlist {
+		entry = stripPort(entry)
+
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			g.allowNets = append(g.allowNets, ipNet)
+			continue
+		}
+
+		if ip := net.ParseIP(entry); ip != nil {
+			g.allowNets = append(g.allowNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+
+		if err := g.allowDomains.add(entry); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	return g, nil
+}
+
+func (g *NetworkGuard) Check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ErrInvalidURL
+	}
+
+	host := normalizeHost(u.Hostname())
+
+	if g.allowDomains.match(host) {
+		return nil
+	}
+
+	if _, ok := g.serviceHosts[host]; ok {
+		return fmt.Errorf("%w: %s", ErrServiceHost, host)
+	}
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+
+	ip, isIP, err := parseIP(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidURL, host)
+	}
+
+	var ips []net.IP
+	if isIP {
+		ips = []net.IP{ip}
+	} else if g.resolver != nil {
+		ips, err = g.lookup(ctx, host)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrUnresolvedHost, host)
+		}
+	}
+
+	// достаточно одного внутреннего адреса: какой из них вернет DNS при переходе - неизвестно
+	for _, ip := range ips {
+		if isInternal(ip) && !g.allowed(ip) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+		}
+	}
+
+	return nil
+}
+
+func (g *NetworkGuard) lookup(ctx context.Context, host string) ([]net.IP, error) {
+	if g.resolveTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.resolveTimeout)
+		defer cancel()
+	}
+
+	addrs, err := g.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]net.IP, 0, len(addrs))
+	for _, a := range addrs {
+		ips = append(ips, a.IP)
+	}
+
+	return ips, nil
+}
+
+func (g *NetworkGuard) allowed(ip net.IP) bool {
+	for _, n := range g.allowNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// internalNets - диапазоны, которых нет в методах net.IP, но которые ведут во внутреннюю сеть
+var internalNets = mustParseCIDRs(
+	"0.0.0.0/8",     // "этот" хост: 0.0.0.1 на linux - это localhost
+	"100.64.0.0/10", // CGNAT, часто внутренняя сеть облака
+	"198.18.0.0/15", // для тестов производительности, встречается во внутренних сетях
+	"64:ff9b::/96",  // NAT64: 64:ff9b::7f00:1 - это 127.0.0.1
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+
+	return nets
+}
+
+func isInternal(ip net.IP) bool {
+	if ip.IsPrivate() ||
+		ip.IsLoopback() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified() {
+		return true
+	}
+
+	for _, n := range internalNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseIP разбирает host так же, как браузер: кроме 127.0.0.1 это 2130706433, 0x7f.1, 0177.0.0.1 и тд.
+// isIP = false - это домен. Ошибка - host похож на ip (заканчивается числом), но браузер его не примет
+func parseIP(host string) (ip net.IP, isIP bool, err error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip, true, nil
+	}
+
+	parts := strings.Split(host, ".")
+	if _, ok := parseIPv4Part(parts[len(parts)-1]); !ok && !isNumber(parts[len(parts)-1]) {
+		return nil, false, nil
+	}
+
+	if len(parts) > 4 {
+		return nil, false, errors.New("too many parts in ipv4 address")
+	}
+
+	numbers := make([]uint64, 0, len(parts))
+	for _, p := range parts {
+		n, ok := parseIPv4Part(p)
+		if !ok {
+			return nil, false, fmt.Errorf("invalid ipv4 part %q", p)
+		}
+		numbers = append(numbers, n)
+	}
+
+	// все части, кроме последней, - по байту, последняя - оставшиеся байты: 127.1 = 127.0.0.1
+	var addr uint64
+	for i, n := range numbers[:len(numbers)-1] {
+		if n > 255 {
+			return nil, false, fmt.Errorf("invalid ipv4 part %q", parts[i])
+		}
+		addr = addr<<8 | n
+	}
+
+	rest := 5 - len(numbers)
+	last := numbers[len(numbers)-1]
+	if last >= 1<<(8*rest) {
+		return nil, false, fmt.Errorf("invalid ipv4 part %q", parts[len(parts)-1])
+	}
+	addr = addr<<(8*rest) | last
+
+	return net.IPv4(byte(addr>>24), byte(addr>>16), byte(addr>>8), byte(addr)), true, nil
+}
+
+// parseIPv4Part - часть ipv4 в десятичной, шестнадцатеричной (0x) или восьмеричной (0) записи
+func parseIPv4Part(p string) (uint64, bool) {
+	if p == "" {
+		return 0, false
+	}
+
+	base := 10
+	switch {
+	case strings.HasPrefix(p, "0x") || strings.HasPrefix(p, "0X"):
+		base, p = 16, p[2:]
+		// "0x" - это 0
+		if p == "" {
+			return 0, true
+		}
+	case len(p) > 1 && p[0] == '0':
+		base, p = 8, p[1:]
+	}
+
+	n, err := strconv.ParseUint(p, base, 64)
+	if err != nil || n > 1<<32 {
+		return 0, false
+	}
+
+	return n, true
+}
+
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// порт в конфиге допустим, но при проверке не учитывается
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return normalizeHost(host)
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
@@ -0,0 +1,192 @@
+package urlpolicy_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/urlpolicy"
+)
+
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	ips, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+
+	addrs := make([]net.IPAddr, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+
+	return addrs, nil
+}
+
+func TestNetworkGuard_Check(t *testing.T) {
+	resolver := fakeResolver{
+		"google.com":         {"142.250.74.46"},
+		"intranet.corp":      {"10.0.0.5"},
+		"wiki.corp":          {"10.1.2.3"},
+		"rebind.example.com": {"93.184.216.34", "127.0.0.1"},
+		"metadata.internal":  {"169.254.169.254"},
+		"v6.example.com":     {"fd00::1"},
+	}
+
+	guard, err := urlpolicy.NewNetworkGuard(
+		resolver,
+		0,
+		[]string{"localhost:8123", "short.example.com"},
+		[]string{"*.corp.example.com", "wiki.corp", "192.168.10.0/24"},
+	)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{name: "Public", url: "https://google.com"},
+		{name: "Public ip", url: "http://8.8.8.8/"},
+		{name: "Metadata ip", url: "http://169.254.169.254/latest/meta-data", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Loopback ip", url: "http://127.0.0.1:8080/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "IPv6 loopback", url: "http://[::1]/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Private ip", url: "http://10.0.0.1/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Unspecified ip", url: "http://0.0.0.0/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Localhost", url: "http://localhost:9000/", wantErr: urlpolicy.ErrServiceHost},
+		{name: "Localhost subdomain", url: "http://app.localhost/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Service host", url: "https://SHORT.example.com/abc", wantErr: urlpolicy.ErrServiceHost},
+		{name: "Resolves to private", url: "https://intranet.corp/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Resolves to link-local", url: "http://metadata.internal/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "One of addresses is loopback", url: "https://rebind.example.com/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Resolves to unique local v6", url: "https://v6.example.com/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Unresolved", url: "https://unknown.example.com/", wantErr: urlpolicy.ErrUnresolvedHost},
+		{name: "Allowed domain", url: "https://wiki.corp/page"},
+		{name: "Allowed wildcard", url: "https://jira.corp.example.com/"},
+		{name: "Allowed subnet", url: "http://192.168.10.20/"},
+		{name: "Not allowed subnet", url: "http://192.168.11.20/", wantErr: urlpolicy.ErrPrivateAddress},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := guard.Check(context.Background(), tc.url)
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestNetworkGuard_WithoutResolver(t *testing.T) {
+	guard, err := urlpolicy.NewNetworkGuard(nil, 0, nil, nil)
+	require.NoError(t, err)
+
+	// без резолвера домены не проверяются, только ip в url
+	require.NoError(t, guard.Check(context.Background(), "https://intranet.corp/"))
+	require.ErrorIs(t, guard.Check(context.Background(), "http://10.0.0.1/"), urlpolicy.ErrPrivateAddress)
+}
+
+func TestNetworkGuard_InternalRanges(t *testing.T) {
+	guard, err := urlpolicy.NewNetworkGuard(nil, 0, nil, nil)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{name: "This network", url: "http://0.0.0.1/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "This network decimal", url: "http://1/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "CGNAT", url: "http://100.64.0.1/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "CGNAT end", url: "http://100.127.255.254/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Benchmarking", url: "http://198.18.0.1/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Benchmarking end", url: "http://198.19.255.254/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "NAT64 loopback", url: "http://[64:ff9b::7f00:1]/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "NAT64 public", url: "http://[64:ff9b::808:808]/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Before CGNAT", url: "http://100.63.255.255/"},
+		{name: "After benchmarking", url: "http://198.20.0.1/"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := guard.Check(context.Background(), tc.url)
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestNetworkGuard_IPv4Forms(t *testing.T) {
+	// без резолвера: адрес из url разбираем так же, как браузер
+	guard, err := urlpolicy.NewNetworkGuard(nil, 0, nil, nil)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{name: "Decimal", url: "http://2130706433/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Hex parts", url: "http://0x7f.1/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Hex number", url: "http://0x7F000001:8080/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Octal", url: "http://0177.0.0.1/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Short", url: "http://127.1/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Short private", url: "http://10.1/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Metadata decimal", url: "http://169.254.43518/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Zero", url: "http://0/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "IPv4-mapped IPv6", url: "http://[::ffff:127.0.0.1]/", wantErr: urlpolicy.ErrPrivateAddress},
+		{name: "Public decimal", url: "http://134744072/"},
+		{name: "Invalid octal", url: "http://08.0.0.1/", wantErr: urlpolicy.ErrInvalidURL},
+		{name: "Part overflow", url: "http://256.0.0.1/", wantErr: urlpolicy.ErrInvalidURL},
+		{name: "Too many parts", url: "http://1.2.3.4.5/", wantErr: urlpolicy.ErrInvalidURL},
+		{name: "Domain with digits", url: "http://123.example/"},
+		{name: "Hex-like domain", url: "http://cafe.abc/"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := guard.Check(context.Background(), tc.url)
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestAll_Check(t *testing.T) {
+	guard, err := urlpolicy.NewNetworkGuard(nil, 0, nil, nil)
+	require.NoError(t, err)
+
+	policy, err := urlpolicy.New(slogdiscard.NewDiscardLogger(), []string{"http", "https"}, "", "")
+	require.NoError(t, err)
+
+	checker := urlpolicy.All{policy, guard}
+
+	require.NoError(t, checker.Check(context.Background(), "https://google.com"))
+	require.ErrorIs(t, checker.Check(context.Background(), "ftp://google.com"), urlpolicy.ErrSchemeNotAllowed)
+	require.ErrorIs(t, checker.Check(context.Background(), "http://127.0.0.1"), urlpolicy.ErrPrivateAddress)
+}
@@ -0,0 +1,308 @@
+package urlpolicy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"url-shortener/internal/lib/logger/sl"
+)
+
+var (
+	ErrInvalidURL       = errors.New("invalid url")
+	ErrSchemeNotAllowed = errors.New("url scheme is not allowed")
+	ErrDomainBlocked    = errors.New("url domain is blocked")
+	ErrDomainNotAllowed = errors.New("url domain is not allowed")
+)
+
+// Checker - одна из проверок url перед сохранением
+type Checker interface {
+	Check(ctx context.Context, rawURL string) error
+}
+
+// All - url должен пройти все проверки по порядку
+type All []Checker
+
+func (a All) Check(ctx context.Context, rawURL string) error {
+	for _, c := range a {
+		if err := c.Check(ctx, rawURL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Policy проверяет, на что можно делать короткие ссылки.
+// Списки доменов читаются из файлов: один домен на строку, # - комментарий.
+// "example.com" - только сам домен, "*.example.com" - все его поддомены
+type Policy struct {
+	log     *slog.Logger
+	schemes map[string]struct{}
+
+	blocklistPath string
+	allowlistPath string
+
+	mu        sync.RWMutex
+	blocklist *domainList
+	allowlist *domainList // nil - разрешены все домены, кроме blocklist
+	modTimes  map[string]time.Time
+}
+
+func New(log *slog.Logger, schemes []string, blocklistPath string, allowlistPath string) (*Policy, error) {
+	const op = "urlpolicy.New"
+
+	p := &Policy{
+		log:      log.With(slog.String("component", "urlpolicy")),
+		modTimes: make(map[string]time.Time),
+	}
+
+	if err := p.Update(schemes, blocklistPath, allowlistPath); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return p, nil
+}
+
+// Check возвращает ошибку, если url нельзя сокращать
+func (p *Policy) Check(_ context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ErrInvalidURL
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	host := normalizeHost(u.Hostname())
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if _, ok := p.schemes[scheme]; !ok {
+		return fmt.Errorf("%w: %s", ErrSchemeNotAllowed, scheme)
+	}
+
+	if p.blocklist.match(host) {
+		return fmt.Errorf("%w: %s", ErrDomainBlocked, host)
+	}
+
+	if p.allowlist != nil && !p.allowlist.match(host) {
+		return fmt.Errorf("%w: %s", ErrDomainNotAllowed, host)
+	}
+
+	return nil
+}
+
+// Update меняет схемы и файлы списков на лету, например при перечитывании конфига.
+// Если новые списки не читаются, остается старая политика целиком
+func (p *Policy) Update(schemes []string, blocklistPath string, allowlistPath string) error {
+	const op = "urlpolicy.Update"
+
+	modTimes := make(map[string]time.Time)
+
+	blocklist, allowlist, err := loadLists(blocklistPath, allowlistPath, modTimes)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	schemeSet := make(map[string]struct{}, len(schemes))
+	for _, s := range schemes {
+		schemeSet[strings.ToLower(s)] = struct{}{}
+	}
+
+	p.mu.Lock()
+	p.schemes = schemeSet
+	p.blocklistPath = blocklistPath
+	p.allowlistPath = allowlistPath
+	p.blocklist = blocklist
+	p.allowlist = allowlist
+	p.modTimes = modTimes
+	p.mu.Unlock()
+
+	return nil
+}
+
+// Reload перечитывает списки доменов. При ошибке остаются старые списки
+func (p *Policy) Reload() error {
+	const op = "urlpolicy.Reload"
+
+	p.mu.RLock()
+	blocklistPath, allowlistPath := p.blocklistPath, p.allowlistPath
+	p.mu.RUnlock()
+
+	modTimes := make(map[string]time.Time)
+
+	blocklist, allowlist, err := loadLists(blocklistPath, allowlistPath, modTimes)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.blocklistPath != blocklistPath || p.allowlistPath != allowlistPath {
+		// пока читали, Update поменял файлы и уже прочитал их
+		return nil
+	}
+
+	if err != nil {
+		// запоминаем время изменения битого файла, чтобы не перечитывать его до следующего изменения
+		for path, t := range modTimes {
+			p.modTimes[path] = t
+		}
+
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	p.blocklist = blocklist
+	p.allowlist = allowlist
+	p.modTimes = modTimes
+
+	return nil
+}
+
+func loadLists(blocklistPath string, allowlistPath string, modTimes map[string]time.Time) (*domainList, *domainList, error) {
+	blocklist, err := loadList(blocklistPath, modTimes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	allowlist, err := loadList(allowlistPath, modTimes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return blocklist, allowlist, nil
+}
+
+// Watch раз в interval проверяет, изменились ли файлы со списками, и перечитывает их
+func (p *Policy) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !p.changed() {
+				continue
+			}
+
+			if err := p.Reload(); err != nil {
+				p.log.Error("failed to reload url policy", sl.Err(err))
+				continue
+			}
+
+			p.log.Info("url policy reloaded")
+		}
+	}
+}
+
+func (p *Policy) changed() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, path := range []string{p.blocklistPath, p.allowlistPath} {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			// пусть Reload залогирует ошибку
+			return true
+		}
+
+		if !info.ModTime().Equal(p.modTimes[path]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type domainList struct {
+	exact    map[string]struct{}
+	suffixes []string // ".example.com" для "*.example.com"
+}
+
+func (l *domainList) add(pattern string) error {
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		if !strings.HasPrefix(suffix, ".") {
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
+		l.suffixes = append(l.suffixes, suffix)
+
+		return nil
+	}
+
+	l.exact[pattern] = struct{}{}
+
+	return nil
+}
+
+func (l *domainList) match(host string) bool {
+	if l == nil {
+		return false
+	}
+
+	if _, ok := l.exact[host]; ok {
+		return true
+	}
+
+	for _, suffix := range l.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// пустой путь - списка нет
+func loadList(path string, modTimes map[string]time.Time) (*domainList, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	modTimes[path] = info.ModTime()
+
+	l := &domainList{exact: make(map[string]struct{})}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = normalizeHost(line)
+		if line == "" {
+			continue
+		}
+
+		if err := l.add(line); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
@@ -0,0 +1,130 @@
+package urlpolicy_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"url-shortener/internal/lib/logger/handlers/slogdiscard"
+	"url-shortener/internal/lib/urlpolicy"
+)
+
+func writeList(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestPolicy_Check(t *testing.T) {
+	blocklist := writeList(t, "blocklist.txt", `
+# phishing
+evil.com
+*.phishing.net # все поддомены
+`)
+
+	policy, err := urlpolicy.New(slogdiscard.NewDiscardLogger(), []string{"http", "https"}, blocklist, "")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		url     string
+		wantErr error
+	}{
+		{name: "Allowed", url: "https://google.com/search?q=1"},
+		{name: "Uppercase scheme", url: "HTTPS://google.com"},
+		{name: "Javascript", url: "javascript:alert(1)", wantErr: urlpolicy.ErrSchemeNotAllowed},
+		{name: "Data", url: "data:text/html;base64,PHNjcmlwdD4=", wantErr: urlpolicy.ErrSchemeNotAllowed},
+		{name: "File", url: "file:///etc/passwd", wantErr: urlpolicy.ErrSchemeNotAllowed},
+		{name: "Blocked domain", url: "https://evil.com/login", wantErr: urlpolicy.ErrDomainBlocked},
+		{name: "Blocked domain with port and case", url: "https://EVIL.com:8443/login", wantErr: urlpolicy.ErrDomainBlocked},
+		{name: "Exact entry does not block subdomain", url: "https://www.evil.com"},
+		{name: "Wildcard subdomain", url: "https://login.bank.phishing.net", wantErr: urlpolicy.ErrDomainBlocked},
+		{name: "Wildcard does not match domain itself", url: "https://phishing.net"},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(context.Background(), tc.url)
+			if tc.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestPolicy_Allowlist(t *testing.T) {
+	allowlist := writeList(t, "allowlist.txt", "*.example.com\nexample.com\n")
+
+	policy, err := urlpolicy.New(slogdiscard.NewDiscardLogger(), []string{"https"}, "", allowlist)
+	require.NoError(t, err)
+
+	require.NoError(t, policy.Check(context.Background(), "https://example.com"))
+	require.NoError(t, policy.Check(context.Background(), "https://docs.example.com"))
+	require.ErrorIs(t, policy.Check(context.Background(), "https://google.com"), urlpolicy.ErrDomainNotAllowed)
+}
+
+func TestPolicy_Watch(t *testing.T) {
+	blocklist := writeList(t, "blocklist.txt", "evil.com\n")
+
+	policy, err := urlpolicy.New(slogdiscard.NewDiscardLogger(), []string{"https"}, blocklist, "")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go policy.Watch(ctx, 10*time.Millisecond)
+
+	require.NoError(t, policy.Check(ctx, "https://bad.org"))
+
+	require.NoError(t, os.WriteFile(blocklist, []byte("evil.com\nbad.org\n"), 0o600))
+	// mtime может не поменяться, если файл перезаписан слишком быстро
+	require.NoError(t, os.Chtimes(blocklist, time.Now(), time.Now().Add(time.Second)))
+
+	require.Eventually(t, func() bool {
+		return policy.Check(ctx, "https://bad.org") != nil
+	}, time.Second, 10*time.Millisecond)
+
+	// битый файл - остается старый список
+	require.NoError(t, os.WriteFile(blocklist, []byte("*bad\n"), 0o600))
+	require.NoError(t, os.Chtimes(blocklist, time.Now(), time.Now().Add(2*time.Second)))
+
+	time.Sleep(50 * time.Millisecond)
+	require.ErrorIs(t, policy.Check(ctx, "https://bad.org"), urlpolicy.ErrDomainBlocked)
+}
+
+func TestPolicy_Update(t *testing.T) {
+	blocklist := writeList(t, "blocklist.txt", "evil.com\n")
+	newBlocklist := writeList(t, "new_blocklist.txt", "bad.org\n")
+	brokenBlocklist := writeList(t, "broken_blocklist.txt", "*bad\n")
+	ctx := context.Background()
+
+	policy, err := urlpolicy.New(slogdiscard.NewDiscardLogger(), []string{"https"}, blocklist, "")
+	require.NoError(t, err)
+
+	require.NoError(t, policy.Update([]string{"http", "https"}, newBlocklist, ""))
+	require.NoError(t, policy.Check(ctx, "http://evil.com"))
+	require.ErrorIs(t, policy.Check(ctx, "https://bad.org"), urlpolicy.ErrDomainBlocked)
+
+	// битый файл - не меняется ничего, в том числе схемы
+	require.Error(t, policy.Update([]string{"https"}, brokenBlocklist, ""))
+	require.NoError(t, policy.Check(ctx, "http://evil.com"))
+	require.ErrorIs(t, policy.Check(ctx, "https://bad.org"), urlpolicy.ErrDomainBlocked)
+}
+
+func TestNew_InvalidPattern(t *testing.T) {
+	blocklist := writeList(t, "blocklist.txt", "*evil.com\n")
+
+	_, err := urlpolicy.New(slogdiscard.NewDiscardLogger(), []string{"https"}, blocklist, "")
+	require.Error(t, err)
+}
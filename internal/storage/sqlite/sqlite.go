@@ -1,53 +1,428 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"strings"
+	"time"
+	"url-shortener/internal/lib/alias"
+	"url-shortener/internal/lib/metrics"
+	"url-shortener/internal/lib/urlnorm"
 	"url-shortener/internal/storage"
 )
 
+// span на каждую операцию, дочерний к span'у запроса из ctx
+var tracer = otel.Tracer("url-shortener/internal/storage/sqlite")
+
 type Storage struct {
 	db *sql.DB
+	// alias_key - по нему ищем и проверяем уникальность alias
+	aliasKey  func(alias string) string
+	conflicts []storage.AliasConflict
 }
 
-func New(storagePath string) (*Storage, error) {
-	const op = "storage.sqlite.New" // для логов и ошибок
-
-	db, err := sql.Open("sqlite3", storagePath)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
-	}
+type Options struct {
+	// NormalizeAliases - alias сравниваются без учета регистра (см. alias.Normalize)
+	NormalizeAliases bool
+	// OnConflict - что делать, если после включения NormalizeAliases alias совпали:
+	// storage.OnConflictFail (по умолчанию), storage.OnConflictKeepFirst или storage.OnConflictRename
+	OnConflict string
+}
 
-	stmt, err := db.Prepare(`
+// миграции применяются по порядку, номер последней примененной хранится в PRAGMA user_version
+var migrations = []string{
+	`
     CREATE TABLE IF NOT EXISTS url(
         id INTEGER PRIMARY KEY,
         alias TEXT NOT NULL UNIQUE,
         url TEXT NOT NULL);
     CREATE INDEX IF NOT EXISTS idx_alias ON url(alias);
-    `)
+    `,
+	`
+    ALTER TABLE url ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+    ALTER TABLE url ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+    CREATE INDEX IF NOT EXISTS idx_owner_created_at ON url(owner, created_at);
+    `,
+	`
+    CREATE TABLE IF NOT EXISTS audit_log(
+        id INTEGER PRIMARY KEY,
+        actor TEXT NOT NULL,
+        action TEXT NOT NULL,
+        alias TEXT NOT NULL,
+        old_value TEXT NOT NULL DEFAULT '',
+        new_value TEXT NOT NULL DEFAULT '',
+        request_id TEXT NOT NULL DEFAULT '',
+        created_at INTEGER NOT NULL);
+    CREATE INDEX IF NOT EXISTS idx_audit_log_alias ON audit_log(alias);
+    CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor);
+    CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+    CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+    BEGIN
+        SELECT RAISE(ABORT, 'audit_log is append-only');
+    END;
+    CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+    BEGIN
+        SELECT RAISE(ABORT, 'audit_log is append-only');
+    END;
+    `,
+	`
+    ALTER TABLE url ADD COLUMN deleted_at INTEGER;
+    CREATE INDEX IF NOT EXISTS idx_deleted_at ON url(deleted_at);
+    `,
+	`
+    ALTER TABLE url ADD COLUMN alias_key TEXT;
+    UPDATE url SET alias_key = alias;
+    CREATE UNIQUE INDEX IF NOT EXISTS idx_alias_key ON url(alias_key);
+    `,
+	`
+    ALTER TABLE url ADD COLUMN url_hash TEXT;
+    CREATE INDEX IF NOT EXISTS idx_owner_url_hash ON url(owner, url_hash);
+    `,
+}
+
+// New
+// При смене режима ключи существующих alias пересчитываются, конфликты разрешаются по opts.OnConflict (см. Conflicts)
+func New(storagePath string, opts Options) (*Storage, error) {
+	const op = "storage.sqlite.New" // для логов и ошибок
+
+	db, err := sql.Open("sqlite3", dsn(storagePath))
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	if _, err := stmt.Exec(); err != nil {
+	if err := migrate(db); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s := &Storage{db: db, aliasKey: exactAlias}
+	if opts.NormalizeAliases {
+		s.aliasKey = alias.Normalize
+	}
+
+	if err := s.rekeyAliases(opts.OnConflict); err != nil {
+		_ = db.Close()
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return &Storage{db: db}, nil
+	if err := s.fillURLHashes(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return s, nil
+}
+
+// busyTimeout - сколько ждать блокировку базы, прежде чем вернуть SQLITE_BUSY.
+// Без него параллельные записи (например, SaveUrl с квотой) сразу падают с "database is locked"
+const busyTimeout = 5 * time.Second
+
+// dsn - путь к базе с параметрами соединения. Параметры в dsn, а не PRAGMA: в пуле database/sql много соединений
+func dsn(storagePath string) string {
+	sep := "?"
+	if strings.Contains(storagePath, "?") {
+		sep = "&"
+	}
+
+	return fmt.Sprintf("%s%s_busy_timeout=%d", storagePath, sep, busyTimeout.Milliseconds())
+}
+
+// Ping - проверка доступности БД для readiness
+func (s *Storage) Ping(ctx context.Context) error {
+	const op = "storage.sqlite.Ping"
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Close закрывает соединение с БД. Вызывать после остановки всех, кто пишет в storage
+func (s *Storage) Close() error {
+	const op = "storage.sqlite.Close"
+
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func exactAlias(alias string) string {
+	return alias
 }
 
-// SaveUrl возвращает index созданной записи
-func (s *Storage) SaveUrl(urlToSave string, alias string) (int64, error) {
+// rekeyAliases приводит alias_key существующих ссылок к текущему режиму.
+// Если ключи совпали у нескольких alias, ключ получает самая старая активная ссылка,
+// совпавшие ссылки из корзины скрываются, остальные - по onConflict. Ссылки из базы не удаляются
+func (s *Storage) rekeyAliases(onConflict string) error {
+	type row struct {
+		id      int64
+		name    string
+		key     sql.NullString // NULL - ссылка проиграла конфликт и по alias не находится
+		deleted bool
+	}
+
+	rows, err := s.db.Query("SELECT id, alias, alias_key, deleted_at IS NOT NULL FROM url ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("rekey aliases: %w", err)
+	}
+
+	var all []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.name, &r.key, &r.deleted); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("rekey aliases: %w", err)
+		}
+		all = append(all, r)
+	}
+	_ = rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rekey aliases: %w", err)
+	}
+
+	// новый ключ -> кому он достанется: самой старой активной ссылке, а если активных нет - самой старой в корзине
+	winners := make(map[string]row, len(all))
+	taken := make(map[string]bool, len(all)) // alias без нормализации тоже уникальны
+	for _, r := range all {
+		taken[r.name] = true
+
+		key := s.aliasKey(r.name)
+		if w, ok := winners[key]; !ok || (w.deleted && !r.deleted) {
+			winners[key] = r
+		}
+	}
+
+	type update struct {
+		id    int64
+		alias string
+		key   sql.NullString
+	}
+
+	var (
+		updates   []update
+		conflicts []storage.AliasConflict
+		failed    []string
+	)
+
+	for _, r := range all {
+		key := s.aliasKey(r.name)
+		w := winners[key]
+
+		if w.id == r.id {
+			if !r.key.Valid || r.key.String != key {
+				updates = append(updates, update{id: r.id, alias: r.name, key: sql.NullString{String: key, Valid: true}})
+			}
+			continue
+		}
+
+		conflict := storage.AliasConflict{Alias: r.name, Kept: w.name, Deleted: r.deleted}
+
+		switch {
+		case r.deleted, onConflict == storage.OnConflictKeepFirst:
+			// остается в базе: после выключения режима или переименования снова доступна,
+			// из корзины удалится как обычно, через trash.retention
+			conflict.Resolution = storage.ResolutionShadowed
+			if r.key.Valid {
+				updates = append(updates, update{id: r.id, alias: r.name})
+			}
+		case onConflict == storage.OnConflictRename:
+			newAlias := freeAlias(r.name, func(name string) bool {
+				_, ok := winners[s.aliasKey(name)]
+				return taken[name] || ok
+			})
+			taken[newAlias] = true
+			winners[s.aliasKey(newAlias)] = row{id: r.id, name: newAlias}
+
+			conflict.Resolution = storage.ResolutionRenamed
+			conflict.NewAlias = newAlias
+			updates = append(updates, update{id: r.id, alias: newAlias, key: sql.NullString{String: s.aliasKey(newAlias), Valid: true}})
+		default:
+			failed = append(failed, w.name+"/"+r.name)
+			continue
+		}
+
+		conflicts = append(conflicts, conflict)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %s", storage.ErrAliasConflict, strings.Join(failed, ", "))
+	}
+	s.conflicts = conflicts
+
+	if len(updates) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("rekey aliases: %w", err)
+	}
+
+	// сначала сбрасываем ключи, чтобы не упереться в уникальный индекс на промежуточном шаге
+	for _, u := range updates {
+		if _, err := tx.Exec("UPDATE url SET alias_key = NULL WHERE id = ?", u.id); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("rekey aliases: %w", err)
+		}
+	}
+	for _, u := range updates {
+		if _, err := tx.Exec("UPDATE url SET alias = ?, alias_key = ? WHERE id = ?", u.alias, u.key, u.id); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("rekey aliases: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// freeAlias - alias-2, alias-3 и тд, первый незанятый
+func freeAlias(alias string, taken func(string) bool) string {
+	for i := 2; ; i++ {
+		if name := fmt.Sprintf("%s-%d", alias, i); !taken(name) {
+			return name
+		}
+	}
+}
+
+// Conflicts - ссылки, alias которых совпали при запуске, и что с ними сделали
+func (s *Storage) Conflicts() []storage.AliasConflict {
+	return s.conflicts
+}
+
+// fillURLHashes считает url_hash для ссылок, созданных до его появления
+func (s *Storage) fillURLHashes() error {
+	rows, err := s.db.Query("SELECT id, url FROM url WHERE url_hash IS NULL")
+	if err != nil {
+		return fmt.Errorf("fill url hashes: %w", err)
+	}
+
+	hashes := make(map[int64]string)
+	for rows.Next() {
+		var (
+			id     int64
+			rawURL string
+		)
+		if err := rows.Scan(&id, &rawURL); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("fill url hashes: %w", err)
+		}
+
+		// такой url не с чем сравнивать, оставляем без хэша
+		if hash, err := urlnorm.Hash(rawURL); err == nil {
+			hashes[id] = hash
+		}
+	}
+	_ = rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("fill url hashes: %w", err)
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("fill url hashes: %w", err)
+	}
+
+	for id, hash := range hashes {
+		if _, err := tx.Exec("UPDATE url SET url_hash = ? WHERE id = ?", hash, id); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("fill url hashes: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("get schema version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+
+		// в mattn/go-sqlite3 Exec выполняет сразу несколько выражений
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// SaveUrl возвращает index созданной записи.
+// Проверка квоты и вставка делаются одним запросом, поэтому параллельные запросы не превысят квоту.
+// entry пишется в журнал в той же транзакции: без записи в журнале ссылка не создается
+func (s *Storage) SaveUrl(
+	ctx context.Context,
+	urlToSave string,
+	alias string,
+	owner string,
+	quota storage.Quota,
+	entry storage.AuditEntry,
+) (_ int64, err error) {
 	const op = "storage.sqlite.SaveUrl"
+	defer metrics.ObserveStorage(op, time.Now())
+
+	ctx, span := tracer.Start(ctx, op)
+	defer endSpan(span, &err)
 
-	stmt, err := s.db.Prepare("INSERT INTO url(url, alias) VALUES (?, ?)")
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
+	// после Commit ничего не делает
+	defer func() { _ = tx.Rollback() }()
 
-	res, err := stmt.Exec(urlToSave, alias)
+	stmt, err := tx.PrepareContext(ctx, `
+    INSERT INTO url(url, url_hash, alias, alias_key, owner, created_at)
+    SELECT ?, ?, ?, ?, ?, ?
+    WHERE (? = 0 OR (SELECT COUNT(*) FROM url WHERE owner = ? AND deleted_at IS NULL) < ?)
+      AND (? = 0 OR (SELECT COUNT(*) FROM url WHERE owner = ? AND created_at >= ?) < ?)
+    `)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// url, который не разобрать, не участвует в поиске дублей
+	var urlHash sql.NullString
+	if hash, err := urlnorm.Hash(urlToSave); err == nil {
+		urlHash = sql.NullString{String: hash, Valid: true}
+	}
+
+	now := time.Now()
+	res, err := stmt.ExecContext(
+		ctx,
+		urlToSave, urlHash, alias, s.aliasKey(alias), owner, now.Unix(),
+		quota.MaxActive, owner, quota.MaxActive,
+		quota.MaxPerDay, owner, startOfDay(now).Unix(), quota.MaxPerDay,
+	)
 	if err != nil {
 		// юзаем библиотеку go-sqlite3
 		// смотрим, что внутри ошибки от sqlite3
@@ -58,45 +433,454 @@ func (s *Storage) SaveUrl(urlToSave string, alias string) (int64, error) {
 		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	if affected == 0 {
+		_ = tx.Rollback()
+
+		// ничего не вставили - уперлись в одну из квот, выясняем в какую
+		usage, err := s.QuotaUsage(ctx, owner)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		if quota.MaxActive > 0 && usage.Active >= quota.MaxActive {
+			return 0, fmt.Errorf("%s: %w", op, storage.ErrActiveQuotaExceeded)
+		}
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrDailyQuotaExceeded)
+	}
+
 	// поддерживается не всеми БД
 	id, err := res.LastInsertId()
 	if err != nil {
 		return 0, fmt.Errorf("%s: faild to get last insert id: %w", op, err)
 	}
 
+	if err := insertAuditEntry(ctx, tx, entry); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
 	return id, nil
 }
 
-func (s *Storage) GetURL(alias string) (string, error) {
+// AliasByURL ищет активную ссылку владельца на тот же url (после urlnorm.Normalize).
+// Если ссылок несколько, возвращает самую старую
+func (s *Storage) AliasByURL(ctx context.Context, urlToFind string, owner string) (_ string, err error) {
+	const op = "storage.sqlite.AliasByURL"
+	defer metrics.ObserveStorage(op, time.Now())
+
+	ctx, span := tracer.Start(ctx, op)
+	defer endSpan(span, &err)
+
+	hash, err := urlnorm.Hash(urlToFind)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, storage.ErrUrlNotFound)
+	}
+
+	stmt, err := s.db.PrepareContext(ctx, `
+    SELECT alias FROM url
+    WHERE owner = ? AND url_hash = ? AND deleted_at IS NULL AND alias_key IS NOT NULL
+    ORDER BY id LIMIT 1
+    `)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var alias string
+	if err := stmt.QueryRowContext(ctx, owner, hash).Scan(&alias); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrUrlNotFound)
+		}
+		return "", fmt.Errorf("%s: execute statement %w", op, err)
+	}
+
+	return alias, nil
+}
+
+// QuotaUsage - сколько активных ссылок есть у пользователя и сколько он создал за текущие сутки (UTC)
+func (s *Storage) QuotaUsage(ctx context.Context, owner string) (_ storage.QuotaUsage, err error) {
+	const op = "storage.sqlite.QuotaUsage"
+	defer metrics.ObserveStorage(op, time.Now())
+
+	ctx, span := tracer.Start(ctx, op)
+	defer endSpan(span, &err)
+
+	stmt, err := s.db.PrepareContext(ctx, `
+    SELECT COUNT(CASE WHEN deleted_at IS NULL THEN 1 END), COUNT(CASE WHEN created_at >= ? THEN 1 END)
+    FROM url WHERE owner = ?
+    `)
+	if err != nil {
+		return storage.QuotaUsage{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var usage storage.QuotaUsage
+	if err := stmt.QueryRowContext(ctx, startOfDay(time.Now()).Unix(), owner).Scan(&usage.Active, &usage.Today); err != nil {
+		return storage.QuotaUsage{}, fmt.Errorf("%s: execute statement %w", op, err)
+	}
+
+	return usage, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// GetURL возвращает storage.ErrUrlDeleted для ссылок в корзине
+func (s *Storage) GetURL(ctx context.Context, alias string) (_ string, err error) {
 	const op = "storage.sqlite.GetUrl"
+	defer metrics.ObserveStorage(op, time.Now())
 
-	stmt, err := s.db.Prepare("SELECT url FROM url WHERE alias = ?")
+	ctx, span := tracer.Start(ctx, op)
+	defer endSpan(span, &err)
+
+	stmt, err := s.db.PrepareContext(ctx, "SELECT url, deleted_at FROM url WHERE alias_key = ?")
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	var urlResult string
-	if err := stmt.QueryRow(alias).Scan(&urlResult); err != nil {
+	var (
+		urlResult string
+		deletedAt sql.NullInt64
+	)
+	if err := stmt.QueryRowContext(ctx, s.aliasKey(alias)).Scan(&urlResult, &deletedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", fmt.Errorf("%s: %w", op, storage.ErrUrlNotFound)
 		}
 		return "", fmt.Errorf("%s: execute statement %w", op, err)
 	}
 
+	if deletedAt.Valid {
+		return "", fmt.Errorf("%s: %w", op, storage.ErrUrlDeleted)
+	}
+
 	return urlResult, nil
 }
 
-func (s *Storage) DeleteUrl(alias string) error {
+// UrlOwner - владелец ссылки, в том числе из корзины
+func (s *Storage) UrlOwner(ctx context.Context, alias string) (_ string, err error) {
+	const op = "storage.sqlite.UrlOwner"
+	defer metrics.ObserveStorage(op, time.Now())
+
+	ctx, span := tracer.Start(ctx, op)
+	defer endSpan(span, &err)
+
+	var owner string
+	if err := s.db.QueryRowContext(ctx, "SELECT owner FROM url WHERE alias_key = ?", s.aliasKey(alias)).Scan(&owner); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrUrlNotFound)
+		}
+		return "", fmt.Errorf("%s: execute statement %w", op, err)
+	}
+
+	return owner, nil
+}
+
+// DeleteUrl переносит ссылку owner в корзину и возвращает ее url.
+// entry с удаленным url в OldValue пишется в журнал в той же транзакции
+func (s *Storage) DeleteUrl(ctx context.Context, alias string, owner string, entry storage.AuditEntry) (_ string, err error) {
 	const op = "storage.sqlite.DeleteUrl"
+	defer metrics.ObserveStorage(op, time.Now())
+
+	ctx, span := tracer.Start(ctx, op)
+	defer endSpan(span, &err)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, `
+    UPDATE url SET deleted_at = ?
+    WHERE alias_key = ? AND owner = ? AND deleted_at IS NULL
+    RETURNING url
+    `)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var deletedURL string
+	if err := stmt.QueryRowContext(ctx, time.Now().Unix(), s.aliasKey(alias), owner).Scan(&deletedURL); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrUrlNotFound)
+		}
+		return "", fmt.Errorf("%s: execute statement %w", op, err)
+	}
+
+	entry.OldValue = deletedURL
+	if err := insertAuditEntry(ctx, tx, entry); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deletedURL, nil
+}
+
+// UpdateUrl меняет url активной ссылки owner и возвращает старый url.
+// entry со старым и новым url пишется в журнал в той же транзакции
+func (s *Storage) UpdateUrl(
+	ctx context.Context,
+	alias string,
+	owner string,
+	urlToSave string,
+	entry storage.AuditEntry,
+) (_ string, err error) {
+	const op = "storage.sqlite.UpdateUrl"
+	defer metrics.ObserveStorage(op, time.Now())
+
+	ctx, span := tracer.Start(ctx, op)
+	defer endSpan(span, &err)
 
-	stmt, err := s.db.Prepare("DELETE FROM url WHERE alias = ?")
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var oldURL string
+	if err := tx.QueryRowContext(ctx,
+		"SELECT url FROM url WHERE alias_key = ? AND owner = ? AND deleted_at IS NULL", s.aliasKey(alias), owner,
+	).Scan(&oldURL); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrUrlNotFound)
+		}
+		return "", fmt.Errorf("%s: execute statement %w", op, err)
+	}
+
+	var urlHash sql.NullString
+	if hash, err := urlnorm.Hash(urlToSave); err == nil {
+		urlHash = sql.NullString{String: hash, Valid: true}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE url SET url = ?, url_hash = ? WHERE alias_key = ? AND owner = ?", urlToSave, urlHash, s.aliasKey(alias), owner,
+	); err != nil {
+		return "", fmt.Errorf("%s: execute statement %w", op, err)
+	}
+
+	entry.OldValue, entry.NewValue = oldURL, urlToSave
+	if err := insertAuditEntry(ctx, tx, entry); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return oldURL, nil
+}
+
+// RestoreUrl достает ссылку owner из корзины и возвращает ее url.
+// Активные ссылки owner не должны превысить quota.MaxActive - квота владельца, а не того, кто восстанавливает.
+// Проверка квоты и восстановление делаются одним запросом.
+// entry с восстановленным url в NewValue пишется в журнал в той же транзакции
+func (s *Storage) RestoreUrl(
+	ctx context.Context,
+	alias string,
+	owner string,
+	quota storage.Quota,
+	entry storage.AuditEntry,
+) (_ string, err error) {
+	const op = "storage.sqlite.RestoreUrl"
+	defer metrics.ObserveStorage(op, time.Now())
+
+	ctx, span := tracer.Start(ctx, op)
+	defer endSpan(span, &err)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, `
+    UPDATE url SET deleted_at = NULL
+    WHERE alias_key = ? AND owner = ? AND deleted_at IS NOT NULL
+      AND (? = 0 OR (SELECT COUNT(*) FROM url AS u WHERE u.owner = url.owner AND u.deleted_at IS NULL) < ?)
+    RETURNING url
+    `)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	var restoredURL string
+	if err := stmt.QueryRowContext(ctx, s.aliasKey(alias), owner, quota.MaxActive, quota.MaxActive).Scan(&restoredURL); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: execute statement %w", op, err)
+		}
+
+		// ничего не обновили - ссылки owner нет в корзине или уперлись в квоту
+		var deleted bool
+		err := tx.QueryRowContext(ctx,
+			"SELECT deleted_at IS NOT NULL FROM url WHERE alias_key = ? AND owner = ?", s.aliasKey(alias), owner,
+		).Scan(&deleted)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%s: execute statement %w", op, err)
+		}
+		if deleted {
+			return "", fmt.Errorf("%s: %w", op, storage.ErrActiveQuotaExceeded)
+		}
+		return "", fmt.Errorf("%s: %w", op, storage.ErrUrlNotFound)
+	}
+
+	entry.NewValue = restoredURL
+	if err := insertAuditEntry(ctx, tx, entry); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return restoredURL, nil
+}
+
+// PurgeDeleted окончательно удаляет ссылки, попавшие в корзину раньше before
+func (s *Storage) PurgeDeleted(ctx context.Context, before time.Time) (_ int64, err error) {
+	const op = "storage.sqlite.PurgeDeleted"
+	defer metrics.ObserveStorage(op, time.Now())
+
+	ctx, span := tracer.Start(ctx, op)
+	defer endSpan(span, &err)
+
+	stmt, err := s.db.PrepareContext(ctx, "DELETE FROM url WHERE deleted_at IS NOT NULL AND deleted_at < ?")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	res, err := stmt.ExecContext(ctx, before.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("%s: execute statement %w", op, err)
+	}
+
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return purged, nil
+}
+
+// SaveAuditEntry - в журнал можно только добавлять, изменение и удаление запрещены триггерами.
+// Изменения ссылок пишут журнал сами, в своей транзакции
+func (s *Storage) SaveAuditEntry(ctx context.Context, entry storage.AuditEntry) (err error) {
+	const op = "storage.sqlite.SaveAuditEntry"
+	defer metrics.ObserveStorage(op, time.Now())
+
+	ctx, span := tracer.Start(ctx, op)
+	defer endSpan(span, &err)
+
+	if err := insertAuditEntry(ctx, s.db, entry); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
-	if _, err := stmt.Exec(alias); err != nil {
-		return fmt.Errorf("%s: execute statement %w", op, err)
+	return nil
+}
+
+// endSpan закрывает span операции. Ошибку пишем в span и помечаем его как ошибочный, иначе в трейсе не видно, что запрос упал
+func endSpan(span trace.Span, err *error) {
+	if *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+// execer - *sql.DB или *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertAuditEntry(ctx context.Context, db execer, entry storage.AuditEntry) error {
+	createdAt := entry.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	if _, err := db.ExecContext(ctx, `
+    INSERT INTO audit_log(actor, action, alias, old_value, new_value, request_id, created_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?)
+    `,
+		entry.Actor, entry.Action, entry.Alias, entry.OldValue, entry.NewValue, entry.RequestID, createdAt.UnixMilli(),
+	); err != nil {
+		return fmt.Errorf("save audit entry: %w", err)
 	}
 
 	return nil
 }
+
+// AuditEntries - записи журнала, новые сначала
+func (s *Storage) AuditEntries(ctx context.Context, filter storage.AuditFilter) (_ []storage.AuditEntry, err error) {
+	const op = "storage.sqlite.AuditEntries"
+	defer metrics.ObserveStorage(op, time.Now())
+
+	ctx, span := tracer.Start(ctx, op)
+	defer endSpan(span, &err)
+
+	query := `
+    SELECT id, actor, action, alias, old_value, new_value, request_id, created_at
+    FROM audit_log WHERE 1 = 1`
+	var args []any
+
+	if filter.Actor != "" {
+		query += " AND actor = ?"
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		query += " AND action = ?"
+		args = append(args, filter.Action)
+	}
+	if filter.Alias != "" {
+		query += " AND alias = ?"
+		args = append(args, filter.Alias)
+	}
+	if !filter.Since.IsZero() {
+		query += " AND created_at >= ?"
+		args = append(args, filter.Since.UnixMilli())
+	}
+	if !filter.Until.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, filter.Until.UnixMilli())
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = -1 // без ограничений
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, filter.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var entries []storage.AuditEntry
+	for rows.Next() {
+		var (
+			e         storage.AuditEntry
+			createdAt int64
+		)
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.Alias, &e.OldValue, &e.NewValue, &e.RequestID, &createdAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		e.CreatedAt = time.UnixMilli(createdAt)
+
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
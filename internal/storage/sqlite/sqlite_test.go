@@ -0,0 +1,642 @@
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"url-shortener/internal/storage"
+	"url-shortener/internal/storage/sqlite"
+)
+
+func newStorage(t *testing.T) *sqlite.Storage {
+	t.Helper()
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{})
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestStorage_SaveUrl_Quota(t *testing.T) {
+	ctx := context.Background()
+
+	s := newStorage(t)
+
+	quota := storage.Quota{MaxActive: 2}
+
+	_, err := s.SaveUrl(ctx, "https://google.com", "a1", "bob", quota, storage.AuditEntry{})
+	require.NoError(t, err)
+	_, err = s.SaveUrl(ctx, "https://google.com", "a2", "bob", quota, storage.AuditEntry{})
+	require.NoError(t, err)
+
+	_, err = s.SaveUrl(ctx, "https://google.com", "a3", "bob", quota, storage.AuditEntry{})
+	require.ErrorIs(t, err, storage.ErrActiveQuotaExceeded)
+
+	// у другого пользователя своя квота
+	_, err = s.SaveUrl(ctx, "https://google.com", "a3", "alice", quota, storage.AuditEntry{})
+	require.NoError(t, err)
+
+	_, err = s.SaveUrl(ctx, "https://google.com", "a4", "alice", storage.Quota{MaxPerDay: 1}, storage.AuditEntry{})
+	require.ErrorIs(t, err, storage.ErrDailyQuotaExceeded)
+
+	usage, err := s.QuotaUsage(ctx, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, storage.QuotaUsage{Active: 2, Today: 2}, usage)
+}
+
+func TestStorage_SaveUrl_QuotaConcurrent(t *testing.T) {
+	ctx := context.Background()
+
+	s := newStorage(t)
+
+	const (
+		workers = 20
+		limit   = 5
+	)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := s.SaveUrl(ctx, "https://google.com", fmt.Sprintf("alias%d", i), "bob", storage.Quota{MaxActive: limit}, storage.AuditEntry{})
+			if err == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+				return
+			}
+			assert.True(t, errors.Is(err, storage.ErrActiveQuotaExceeded), err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, limit, created)
+
+	usage, err := s.QuotaUsage(ctx, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, limit, usage.Active)
+}
+
+func TestStorage_AuditLog(t *testing.T) {
+	ctx := context.Background()
+
+	s := newStorage(t)
+
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := []storage.AuditEntry{
+		{Actor: "bob", Action: "create", Alias: "a1", NewValue: "https://google.com", RequestID: "req-1", CreatedAt: base},
+		{Actor: "bob", Action: "delete", Alias: "a1", OldValue: "https://google.com", RequestID: "req-2", CreatedAt: base.Add(time.Hour)},
+		{Actor: "alice", Action: "create", Alias: "a2", NewValue: "https://ya.ru", RequestID: "req-3", CreatedAt: base.Add(2 * time.Hour)},
+	}
+	for _, e := range entries {
+		require.NoError(t, s.SaveAuditEntry(ctx, e))
+	}
+
+	got, err := s.AuditEntries(ctx, storage.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	// новые сначала
+	assert.Equal(t, "req-3", got[0].RequestID)
+	assert.True(t, got[0].CreatedAt.Equal(entries[2].CreatedAt))
+
+	got, err = s.AuditEntries(ctx, storage.AuditFilter{Actor: "bob", Action: "delete"})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "https://google.com", got[0].OldValue)
+
+	got, err = s.AuditEntries(ctx, storage.AuditFilter{Since: base.Add(30 * time.Minute), Until: base.Add(90 * time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "req-2", got[0].RequestID)
+
+	got, err = s.AuditEntries(ctx, storage.AuditFilter{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "req-2", got[0].RequestID)
+}
+
+func TestStorage_DeleteRestore(t *testing.T) {
+	ctx := context.Background()
+
+	s := newStorage(t)
+
+	_, err := s.SaveUrl(ctx, "https://google.com", "a1", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+
+	deleted, err := s.DeleteUrl(ctx, "a1", "bob", storage.AuditEntry{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", deleted)
+
+	_, err = s.DeleteUrl(ctx, "a1", "bob", storage.AuditEntry{})
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = s.DeleteUrl(ctx, "missing", "bob", storage.AuditEntry{})
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = s.GetURL(ctx, "a1")
+	require.ErrorIs(t, err, storage.ErrUrlDeleted)
+
+	// alias занят, пока ссылка в корзине
+	_, err = s.SaveUrl(ctx, "https://ya.ru", "a1", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.ErrorIs(t, err, storage.ErrUrlExists)
+
+	usage, err := s.QuotaUsage(ctx, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, storage.QuotaUsage{Active: 0, Today: 1}, usage)
+
+	restored, err := s.RestoreUrl(ctx, "a1", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", restored)
+
+	got, err := s.GetURL(ctx, "a1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", got)
+
+	_, err = s.RestoreUrl(ctx, "a1", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func TestStorage_RestoreUrl_Quota(t *testing.T) {
+	ctx := context.Background()
+
+	s := newStorage(t)
+
+	quota := storage.Quota{MaxActive: 1}
+
+	_, err := s.SaveUrl(ctx, "https://google.com", "a1", "bob", quota, storage.AuditEntry{})
+	require.NoError(t, err)
+	_, err = s.DeleteUrl(ctx, "a1", "bob", storage.AuditEntry{})
+	require.NoError(t, err)
+	_, err = s.SaveUrl(ctx, "https://google.com", "a2", "bob", quota, storage.AuditEntry{})
+	require.NoError(t, err)
+
+	_, err = s.RestoreUrl(ctx, "a1", "bob", quota, storage.AuditEntry{})
+	require.ErrorIs(t, err, storage.ErrActiveQuotaExceeded)
+}
+
+func TestStorage_OtherOwner(t *testing.T) {
+	ctx := context.Background()
+
+	s := newStorage(t)
+
+	_, err := s.SaveUrl(ctx, "https://google.com", "a1", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+
+	owner, err := s.UrlOwner(ctx, "a1")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", owner)
+
+	_, err = s.UrlOwner(ctx, "missing")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	// чужую ссылку не удалить и не восстановить
+	_, err = s.DeleteUrl(ctx, "a1", "alice", storage.AuditEntry{})
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = s.DeleteUrl(ctx, "a1", "bob", storage.AuditEntry{})
+	require.NoError(t, err)
+
+	_, err = s.RestoreUrl(ctx, "a1", "alice", storage.Quota{}, storage.AuditEntry{})
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	// удаленная ссылка тоже принадлежит владельцу
+	owner, err = s.UrlOwner(ctx, "a1")
+	require.NoError(t, err)
+	assert.Equal(t, "bob", owner)
+}
+
+func TestStorage_UpdateUrl(t *testing.T) {
+	ctx := context.Background()
+
+	s := newStorage(t)
+
+	_, err := s.SaveUrl(ctx, "https://google.com", "a1", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+
+	old, err := s.UpdateUrl(ctx, "a1", "bob", "https://ya.ru", storage.AuditEntry{Actor: "bob", Action: "update", Alias: "a1"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", old)
+
+	got, err := s.GetURL(ctx, "a1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru", got)
+
+	alias, err := s.AliasByURL(ctx, "https://ya.ru", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "a1", alias)
+
+	entries, err := s.AuditEntries(ctx, storage.AuditFilter{Action: "update"})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "https://google.com", entries[0].OldValue)
+	assert.Equal(t, "https://ya.ru", entries[0].NewValue)
+
+	_, err = s.UpdateUrl(ctx, "a1", "alice", "https://go.dev", storage.AuditEntry{})
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = s.DeleteUrl(ctx, "a1", "bob", storage.AuditEntry{})
+	require.NoError(t, err)
+	_, err = s.UpdateUrl(ctx, "a1", "bob", "https://go.dev", storage.AuditEntry{})
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func TestStorage_PurgeDeleted(t *testing.T) {
+	ctx := context.Background()
+
+	s := newStorage(t)
+
+	_, err := s.SaveUrl(ctx, "https://google.com", "a1", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+	_, err = s.SaveUrl(ctx, "https://google.com", "a2", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+	_, err = s.DeleteUrl(ctx, "a1", "bob", storage.AuditEntry{})
+	require.NoError(t, err)
+
+	purged, err := s.PurgeDeleted(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	purged, err = s.PurgeDeleted(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	_, err = s.GetURL(ctx, "a1")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = s.GetURL(ctx, "a2")
+	require.NoError(t, err)
+}
+
+func TestStorage_AuditLogAppendOnly(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+	require.NoError(t, s.SaveAuditEntry(ctx, storage.AuditEntry{Actor: "bob", Action: "create", Alias: "a1"}))
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	_, err = db.Exec("UPDATE audit_log SET actor = 'alice'")
+	require.ErrorContains(t, err, "append-only")
+
+	_, err = db.Exec("DELETE FROM audit_log")
+	require.ErrorContains(t, err, "append-only")
+}
+
+func TestStorage_AuditInTransaction(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+
+	_, err = s.SaveUrl(ctx, "https://google.com", "a1", "bob", storage.Quota{},
+		storage.AuditEntry{Actor: "bob", Action: "create", Alias: "a1", NewValue: "https://google.com"})
+	require.NoError(t, err)
+	_, err = s.DeleteUrl(ctx, "a1", "bob", storage.AuditEntry{Actor: "bob", Action: "delete", Alias: "a1"})
+	require.NoError(t, err)
+	_, err = s.RestoreUrl(ctx, "a1", "bob", storage.Quota{}, storage.AuditEntry{Actor: "bob", Action: "restore", Alias: "a1"})
+	require.NoError(t, err)
+
+	got, err := s.AuditEntries(ctx, storage.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, "https://google.com", got[0].NewValue) // restore
+	assert.Equal(t, "https://google.com", got[1].OldValue) // delete
+
+	// журнал не пишется - изменение откатывается
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	_, err = db.Exec(`CREATE TRIGGER audit_log_broken BEFORE INSERT ON audit_log BEGIN SELECT RAISE(ABORT, 'broken'); END`)
+	require.NoError(t, err)
+
+	_, err = s.SaveUrl(ctx, "https://ya.ru", "a2", "bob", storage.Quota{}, storage.AuditEntry{Action: "create"})
+	require.ErrorContains(t, err, "broken")
+	_, err = s.GetURL(ctx, "a2")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = s.DeleteUrl(ctx, "a1", "bob", storage.AuditEntry{Action: "delete"})
+	require.ErrorContains(t, err, "broken")
+	_, err = s.GetURL(ctx, "a1")
+	require.NoError(t, err)
+}
+
+func TestStorage_CaseInsensitiveAliases(t *testing.T) {
+	ctx := context.Background()
+
+	s, err := sqlite.New(filepath.Join(t.TempDir(), "storage.db"), sqlite.Options{NormalizeAliases: true})
+	require.NoError(t, err)
+
+	_, err = s.SaveUrl(ctx, "https://google.com", "ABC123", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+
+	got, err := s.GetURL(ctx, "abc123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", got)
+
+	_, err = s.GetURL(ctx, " Abc123 ")
+	require.NoError(t, err)
+
+	_, err = s.SaveUrl(ctx, "https://ya.ru", "abc123", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.ErrorIs(t, err, storage.ErrUrlExists)
+
+	_, err = s.DeleteUrl(ctx, "abc123", "bob", storage.AuditEntry{})
+	require.NoError(t, err)
+	_, err = s.RestoreUrl(ctx, "aBc123", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+}
+
+func TestStorage_SwitchAliasMode(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+
+	_, err = s.SaveUrl(ctx, "https://google.com", "Abc", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+
+	_, err = s.GetURL(ctx, "abc")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	// существующие alias пересчитываются при включении режима
+	s, err = sqlite.New(path, sqlite.Options{NormalizeAliases: true})
+	require.NoError(t, err)
+
+	_, err = s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+
+	// и обратно
+	s, err = sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+
+	_, err = s.GetURL(ctx, "abc")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	_, err = s.SaveUrl(ctx, "https://ya.ru", "abc", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+
+	_, err = sqlite.New(path, sqlite.Options{NormalizeAliases: true, OnConflict: storage.OnConflictFail})
+	require.ErrorIs(t, err, storage.ErrAliasConflict)
+	require.ErrorContains(t, err, "Abc/abc")
+
+	// после конфликта режим не поменялся
+	s, err = sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+
+	got, err := s.GetURL(ctx, "Abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", got)
+}
+
+// conflictingStorage - три alias, которые совпадают без учета регистра, третий в корзине
+func conflictingStorage(t *testing.T) string {
+	t.Helper()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+
+	_, err = s.SaveUrl(ctx, "https://google.com", "Abc", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+	_, err = s.SaveUrl(ctx, "https://ya.ru", "abc", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+	_, err = s.SaveUrl(ctx, "https://go.dev", "ABC", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+	_, err = s.DeleteUrl(ctx, "ABC", "bob", storage.AuditEntry{})
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	return path
+}
+
+func TestStorage_AliasConflict_KeepFirst(t *testing.T) {
+	ctx := context.Background()
+
+	path := conflictingStorage(t)
+
+	s, err := sqlite.New(path, sqlite.Options{NormalizeAliases: true, OnConflict: storage.OnConflictKeepFirst})
+	require.NoError(t, err)
+
+	want := []storage.AliasConflict{
+		{Alias: "abc", Kept: "Abc", Resolution: storage.ResolutionShadowed},
+		{Alias: "ABC", Kept: "Abc", Resolution: storage.ResolutionShadowed, Deleted: true},
+	}
+	assert.Equal(t, want, s.Conflicts())
+
+	got, err := s.GetURL(ctx, "ABC")
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", got)
+
+	// скрытый alias не отдаем как уже существующую ссылку
+	_, err = s.AliasByURL(ctx, "https://ya.ru", "bob")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	// при следующем запуске конфликты те же: скрытые ссылки никуда не делись
+	s, err = sqlite.New(path, sqlite.Options{NormalizeAliases: true, OnConflict: storage.OnConflictKeepFirst})
+	require.NoError(t, err)
+	assert.Equal(t, want, s.Conflicts())
+
+	// без режима скрытые ссылки снова доступны, в том числе в корзине
+	s, err = sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+	assert.Empty(t, s.Conflicts())
+
+	got, err = s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru", got)
+
+	_, err = s.GetURL(ctx, "ABC")
+	require.ErrorIs(t, err, storage.ErrUrlDeleted)
+}
+
+func TestStorage_AliasConflict_FailByDefault(t *testing.T) {
+	ctx := context.Background()
+
+	path := conflictingStorage(t)
+
+	_, err := sqlite.New(path, sqlite.Options{NormalizeAliases: true})
+	require.ErrorIs(t, err, storage.ErrAliasConflict)
+	require.ErrorContains(t, err, "Abc/abc")
+
+	// ничего не поменялось
+	s, err := sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+
+	for alias, want := range map[string]string{"Abc": "https://google.com", "abc": "https://ya.ru"} {
+		got, err := s.GetURL(ctx, alias)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+
+	_, err = s.GetURL(ctx, "ABC")
+	require.ErrorIs(t, err, storage.ErrUrlDeleted)
+}
+
+func TestStorage_AliasConflict_Rename(t *testing.T) {
+	ctx := context.Background()
+
+	path := conflictingStorage(t)
+
+	s, err := sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+	// abc-2 занят, переименуем в abc-3
+	_, err = s.SaveUrl(ctx, "https://example.com", "ABC-2", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+
+	s, err = sqlite.New(path, sqlite.Options{NormalizeAliases: true, OnConflict: storage.OnConflictRename})
+	require.NoError(t, err)
+
+	assert.Equal(t, []storage.AliasConflict{
+		{Alias: "abc", Kept: "Abc", Resolution: storage.ResolutionRenamed, NewAlias: "abc-3"},
+		{Alias: "ABC", Kept: "Abc", Resolution: storage.ResolutionShadowed, Deleted: true},
+	}, s.Conflicts())
+
+	got, err := s.GetURL(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, "https://google.com", got)
+
+	got, err = s.GetURL(ctx, "ABC-3")
+	require.NoError(t, err)
+	assert.Equal(t, "https://ya.ru", got)
+
+	// переименованные больше не конфликтуют, скрытая в корзине - по-прежнему
+	s, err = sqlite.New(path, sqlite.Options{NormalizeAliases: true, OnConflict: storage.OnConflictRename})
+	require.NoError(t, err)
+	assert.Equal(t, []storage.AliasConflict{
+		{Alias: "ABC", Kept: "Abc", Resolution: storage.ResolutionShadowed, Deleted: true},
+	}, s.Conflicts())
+}
+
+func TestStorage_AliasByURL(t *testing.T) {
+	ctx := context.Background()
+
+	s := newStorage(t)
+
+	_, err := s.SaveUrl(ctx, "https://google.com/search?q=go&hl=en", "a1", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+	_, err = s.SaveUrl(ctx, "https://google.com/search?q=go&hl=en", "a2", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+
+	got, err := s.AliasByURL(ctx, "HTTPS://Google.com:443/search?hl=en&q=go", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "a1", got)
+
+	// чужие ссылки не переиспользуем
+	_, err = s.AliasByURL(ctx, "https://google.com/search?q=go&hl=en", "alice")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	// удаленные тоже
+	_, err = s.DeleteUrl(ctx, "a1", "bob", storage.AuditEntry{})
+	require.NoError(t, err)
+
+	got, err = s.AliasByURL(ctx, "https://google.com/search?q=go&hl=en", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "a2", got)
+}
+
+func TestStorage_FillURLHashes(t *testing.T) {
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "storage.db")
+
+	s, err := sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+
+	_, err = s.SaveUrl(ctx, "https://google.com", "a1", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+
+	// ссылка, созданная до появления url_hash
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	_, err = db.Exec("UPDATE url SET url_hash = NULL")
+	require.NoError(t, err)
+
+	_, err = s.AliasByURL(ctx, "https://google.com", "bob")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	s, err = sqlite.New(path, sqlite.Options{})
+	require.NoError(t, err)
+
+	got, err := s.AliasByURL(ctx, "https://google.com/", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "a1", got)
+}
+
+func TestStorage_SpanStatus(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx := context.Background()
+
+	s := newStorage(t)
+
+	_, err := s.SaveUrl(ctx, "https://google.com", "a1", "bob", storage.Quota{}, storage.AuditEntry{})
+	require.NoError(t, err)
+	_, err = s.GetURL(ctx, "missing")
+	require.ErrorIs(t, err, storage.ErrUrlNotFound)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	require.Equal(t, "storage.sqlite.SaveUrl", spans[0].Name())
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+
+	require.Equal(t, "storage.sqlite.GetUrl", spans[1].Name())
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	require.Contains(t, spans[1].Status().Description, storage.ErrUrlNotFound.Error())
+	require.Len(t, spans[1].Events(), 1) // RecordError
+}
+
+func TestStorage_Close(t *testing.T) {
+	ctx := context.Background()
+
+	s := newStorage(t)
+
+	require.NoError(t, s.Close())
+
+	_, err := s.GetURL(ctx, "a1")
+	require.Error(t, err)
+	require.NotErrorIs(t, err, storage.ErrUrlNotFound)
+}
+
+func TestStorage_Ping(t *testing.T) {
+	s := newStorage(t)
+
+	require.NoError(t, s.Ping(context.Background()))
+
+	require.NoError(t, s.Close())
+	require.Error(t, s.Ping(context.Background()))
+}
@@ -1,8 +1,75 @@
 package storage
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrUrlNotFound = errors.New("url not found")
 	ErrUrlExists   = errors.New("url exists")
+	ErrUrlDeleted  = errors.New("url deleted")
+
+	// после нормализации несколько alias стали одинаковыми
+	ErrAliasConflict = errors.New("aliases conflict after normalization")
+
+	ErrActiveQuotaExceeded = errors.New("active links quota exceeded")
+	ErrDailyQuotaExceeded  = errors.New("daily links quota exceeded")
+)
+
+// что делать с alias, которые совпали после нормализации. Ссылки в корзине при конфликте скрываются всегда
+const (
+	OnConflictKeepFirst = "keep_first" // alias остается у самой старой ссылки, остальные недоступны до переименования
+	OnConflictRename    = "rename"     // остальные получают свободный alias с суффиксом: abc-2, abc-3
+	OnConflictFail      = "fail"       // storage не запускается, режим не меняется
+)
+
+// что стало со ссылкой, проигравшей конфликт
+const (
+	ResolutionShadowed = "shadowed" // осталась в базе, но по alias не находится
+	ResolutionRenamed  = "renamed"
 )
+
+// AliasConflict - ссылка, alias которой совпал с alias более старой ссылки
+type AliasConflict struct {
+	Alias      string
+	Kept       string // alias, который остался доступен
+	Resolution string
+	NewAlias   string // для ResolutionRenamed
+	Deleted    bool   // ссылка в корзине
+}
+
+// Quota - ограничения на количество ссылок пользователя. 0 - без ограничений
+type Quota struct {
+	MaxActive int
+	MaxPerDay int
+}
+
+// QuotaUsage - сколько ссылок пользователь уже создал
+type QuotaUsage struct {
+	Active int // без удаленных
+	Today  int // включая удаленные
+}
+
+// AuditEntry - запись журнала изменений ссылок
+type AuditEntry struct {
+	ID        int64
+	Actor     string // кто сделал изменение
+	Action    string
+	Alias     string
+	OldValue  string
+	NewValue  string
+	RequestID string
+	CreatedAt time.Time
+}
+
+// AuditFilter - пустые поля не участвуют в фильтрации
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Alias  string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
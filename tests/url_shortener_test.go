@@ -119,6 +119,51 @@ func TestURLShortener_SaveRedirect(t *testing.T) {
 	}
 }
 
+func TestURLShortener_DeleteRestore(t *testing.T) {
+	u := url.URL{
+		Scheme: "http",
+		Host:   host,
+	}
+	e := httpexpect.Default(t, u.String())
+
+	urlToSave := gofakeit.URL()
+	alias := random.NewRandomString(10)
+
+	e.POST("/url").
+		WithJSON(save.Request{
+			URL:   urlToSave,
+			Alias: alias,
+		}).
+		WithBasicAuth("admin", "qwerty").
+		Expect().
+		Status(http.StatusOK)
+
+	e.DELETE("/"+path.Join("url", alias)).
+		WithBasicAuth("admin", "qwerty").
+		Expect().
+		Status(http.StatusOK)
+
+	// удаленная ссылка
+	e.GET("/" + alias).
+		Expect().
+		Status(http.StatusGone)
+
+	// повторное удаление
+	e.DELETE("/"+path.Join("url", alias)).
+		WithBasicAuth("admin", "qwerty").
+		Expect().
+		Status(http.StatusNotFound)
+
+	e.POST("/"+path.Join("url", alias, "restore")).
+		WithBasicAuth("admin", "qwerty").
+		Expect().
+		Status(http.StatusOK).
+		JSON().Object().
+		Value("url").String().IsEqual(urlToSave)
+
+	testRedirect(t, alias, urlToSave)
+}
+
 func testRedirectNotFound(t *testing.T, alias string) {
 	u := url.URL{
 		Scheme: "http",